@@ -2,8 +2,14 @@
 package gogettext
 
 import (
-	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -33,186 +39,750 @@ const (
 	// ErrorNilMessageCatalog indicates that the underlying data is nil and has not been loaded.
 	ErrorNilMessageCatalog = Error("message catalog is nil")
 	// ErrorMsgctxtTypeAssertionFailed indicates the underlying data is structured incorrectly.
+	//
+	// Deprecated: The MessageCatalog stores typed data, so this error is no
+	// longer returned.
 	ErrorMsgctxtTypeAssertionFailed = Error("message context type assertion failed")
 	// ErrorMsgidTypeAssertionFailed indicates the underlying data is structured incorrectly.
+	//
+	// Deprecated: The MessageCatalog stores typed data, so this error is no
+	// longer returned.
 	ErrorMsgidTypeAssertionFailed = Error("message identifier type assertion failed")
 	// ErrorPluralsTypeAssertionFailed indicates the underlying data is structured incorrectly.
+	//
+	// Deprecated: The MessageCatalog stores typed data, so this error is no
+	// longer returned.
 	ErrorPluralsTypeAssertionFailed = Error("plurals type assertion failed")
 	// ErrorTranslationTypeAssertionFailed indicates the underlying data is structured incorrectly.
+	//
+	// Deprecated: The MessageCatalog stores typed data, so this error is no
+	// longer returned.
 	ErrorTranslationTypeAssertionFailed = Error("translation type assertion failed")
+	// ErrorFuzzyTranslation indicates that the specified msgid was found in the MessageCatalog,
+	// but it is marked as fuzzy and the MessageCatalog was not loaded with UseFuzzy.
+	ErrorFuzzyTranslation = Error("translation is fuzzy")
 	// ErrorPluralsIndexOutOfBounds indicates that evaluation of the Plural-Forms header resulted in
 	// an index outside of the bounds of the provided plural list.
 	ErrorPluralsIndexOutOfBounds = Error("plural index out of bounds")
+	// ErrorPluralFormsOutOfRange indicates that the plural expression of the Plural-Forms header
+	// evaluates to an index that is not less than its nplurals for some quantity.
+	ErrorPluralFormsOutOfRange = Error("plural expression exceeds nplurals")
+	// ErrorPluralCountMismatch indicates that the MessageCatalog was loaded with StrictPlurals and
+	// contains an entry whose number of plural translations differs from nplurals.
+	ErrorPluralCountMismatch = Error("plural count differs from nplurals")
+	// ErrorInvalidQuantity indicates that a float64 quantity is not finite or can't be described by
+	// the CLDR plural operands, whose integer digits must fit in a uint64 and whose fraction may
+	// have at most maxFractionDigits digits.
+	ErrorInvalidQuantity = Error("invalid quantity")
 
 	defaultPluralForms = "n==1 ? 0 : 1"
+	defaultNPlurals    = 2
+
+	// pluralRangeLimit is the quantity up to which every plural expression is
+	// checked against nplurals, as msgfmt --check does.
+	pluralRangeLimit = 1000
+
+	// maxFractionDigits is the largest number of fraction digits that
+	// pluralsparser.ParseOperands accepts.
+	maxFractionDigits = 18
 )
 
 var (
-	pluralFormsRegex = regexp.MustCompile(`nplurals\s*=\s*\d+;\s*plural\s*=\s*([n0-9%!=&|?:><+() \-]+);`)
+	defaultPluralExpr = pluralsparser.MustCompile(defaultPluralForms)
+
+	// pluralRangeQuantities contains the quantities beyond pluralRangeLimit at
+	// which plural expressions are also checked against nplurals.
+	pluralRangeQuantities = []uint64{1e4, 1e5, 1e6, 1e9, 1e12, 1e15, 1e18, math.MaxUint64}
 )
 
 // MessageCatalog is a struct that contains the data imported from a gettext
-// Portable Object file and ensures thread safety.
+// Portable Object (.po) or Machine Object (.mo) file and ensures thread safety.
 type MessageCatalog struct {
-	messages    map[string]interface{}
+	store       store
 	mutex       sync.RWMutex
-	pluralForms string
+	pluralForms *pluralsparser.Expr
+	// decimalPluralForms is used instead of pluralForms for quantities with
+	// a fraction. It is a rule of decimalPluralRules if pluralRule comes
+	// from the built-in table or agrees with it, and pluralForms otherwise.
+	decimalPluralForms *pluralsparser.Expr
+	pluralRule         PluralRule
+	useFuzzy           bool
+	strict             bool
+	language           string
 }
 
 // NewMessageCatalogFromFile creates a MessageCatalog from a gettext Portable
 // Object (.po) file.
 //
 // An error is returned if the data is in an invalid format.
-func NewMessageCatalogFromFile(filePath string) (*MessageCatalog, error) {
-	mc := &MessageCatalog{}
-	var err error
+func NewMessageCatalogFromFile(filePath string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".po", func() (*po2json.Catalog, error) {
+		return po2json.ParseFile(filePath)
+	}, options)
+}
 
-	mc.mutex.Lock()
-	mc.messages, err = po2json.LoadFile(filePath)
-	mc.mutex.Unlock()
+// NewMessageCatalogFromFS creates a MessageCatalog from the gettext Portable
+// Object (.po) file at filePath within fsys, such as an embed.FS.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format.
+func NewMessageCatalogFromFS(fsys fs.FS, filePath string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".po", func() (*po2json.Catalog, error) {
+		return po2json.ParseFS(fsys, filePath)
+	}, options)
+}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to load .po file")
-	}
+// NewMessageCatalogsFromFS walks the directory dir within fsys and creates a
+// MessageCatalog from every gettext Portable Object (.po) file found in it or
+// in any of its subdirectories. The catalogs are keyed by their path within
+// fsys. The same options are applied to every catalog.
+//
+// An error is returned if dir can't be walked or if any of the files is in an
+// invalid format.
+func NewMessageCatalogsFromFS(fsys fs.FS, dir string, options ...Option) (map[string]*MessageCatalog, error) {
+	catalogs := map[string]*MessageCatalog{}
+	err := fs.WalkDir(fsys, dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-	if err := mc.setPluralForms(); err != nil {
-		return nil, errors.Wrap(err, "failed to set plural forms")
+		if d.IsDir() || path.Ext(filePath) != ".po" {
+			return nil
+		}
+
+		mc, err := NewMessageCatalogFromFS(fsys, filePath, options...)
+		if err != nil {
+			return err
+		}
+
+		catalogs[filePath] = mc
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return mc, nil
+	return catalogs, nil
 }
 
 // NewMessageCatalogFromString creates a MessageCatalog from the string representation
 // of a gettext Portable Object (.po) file.
 //
 // An error is returned if the data is in an invalid format.
-func NewMessageCatalogFromString(fileContents string) (*MessageCatalog, error) {
-	mc := &MessageCatalog{}
-	var err error
+func NewMessageCatalogFromString(fileContents string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".po", func() (*po2json.Catalog, error) {
+		return po2json.ParseString(fileContents)
+	}, options)
+}
 
-	mc.mutex.Lock()
-	mc.messages, err = po2json.LoadString(fileContents)
-	mc.mutex.Unlock()
+// NewMessageCatalogFromBytes creates a MessageCatalog from the []byte representation
+// of a gettext Portable Object (.po) file.
+//
+// An error is returned if the data is in an invalid format.
+func NewMessageCatalogFromBytes(fileContents []byte, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".po", func() (*po2json.Catalog, error) {
+		return po2json.ParseBytes(fileContents)
+	}, options)
+}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to load .po file")
-	}
+// NewMessageCatalogFromReader creates a MessageCatalog from a gettext Portable
+// Object (.po) file read from r. The file is parsed incrementally as it is
+// read rather than being loaded into memory in its entirety.
+//
+// An error is returned if reading from r fails or if the data is in an
+// invalid format.
+func NewMessageCatalogFromReader(r io.Reader, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".po", func() (*po2json.Catalog, error) {
+		return po2json.ParseReader(r)
+	}, options)
+}
 
-	if err := mc.setPluralForms(); err != nil {
-		return nil, errors.Wrap(err, "failed to set plural forms")
-	}
+// NewMessageCatalogFromMOFile creates a MessageCatalog from a compiled gettext
+// Machine Object (.mo) file, such as one produced by msgfmt.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format.
+func NewMessageCatalogFromMOFile(filePath string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".mo", func() (*po2json.Catalog, error) {
+		return po2json.ParseMOFile(filePath)
+	}, options)
+}
 
-	return mc, nil
+// NewMessageCatalogFromMOFS creates a MessageCatalog from the compiled gettext
+// Machine Object (.mo) file at filePath within fsys, such as an embed.FS.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format.
+func NewMessageCatalogFromMOFS(fsys fs.FS, filePath string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".mo", func() (*po2json.Catalog, error) {
+		return po2json.ParseMOFS(fsys, filePath)
+	}, options)
 }
 
-// NewMessageCatalogFromBytes creates a MessageCatalog from the []byte representation
-// of a gettext Portable Object (.po) file.
+// NewMessageCatalogFromMOBytes creates a MessageCatalog from the []byte
+// representation of a compiled gettext Machine Object (.mo) file.
 //
 // An error is returned if the data is in an invalid format.
-func NewMessageCatalogFromBytes(fileContents []byte) (*MessageCatalog, error) {
-	mc := &MessageCatalog{}
-	var err error
+func NewMessageCatalogFromMOBytes(fileContents []byte, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".mo", func() (*po2json.Catalog, error) {
+		return po2json.ParseMOBytes(fileContents)
+	}, options)
+}
 
-	mc.mutex.Lock()
-	mc.messages, err = po2json.LoadBytes(fileContents)
-	mc.mutex.Unlock()
+// NewMessageCatalogFromMOReader creates a MessageCatalog from a compiled
+// gettext Machine Object (.mo) file read from r.
+//
+// An error is returned if reading from r fails or if the data is in an
+// invalid format.
+func NewMessageCatalogFromMOReader(r io.Reader, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalog(".mo", func() (*po2json.Catalog, error) {
+		return po2json.ParseMOReader(r)
+	}, options)
+}
+
+// NewMappedMessageCatalog creates a read-only MessageCatalog that maps the
+// compiled gettext Machine Object (.mo) file at filePath into memory instead
+// of loading it. Translations are looked up in place with the hash table of
+// the file, or by binary search if it has none, and only the strings that are
+// returned are copied. This keeps large catalogs out of the heap.
+//
+// SearchMsgids and the StrictPlurals check copy one message out of the file at
+// a time. GetMessages, WritePO and WriteMO need the whole file, which they
+// parse onto the heap the first time that one of them is called and keep
+// until Close, so that a mapped catalog costs as much memory as a loaded one
+// from then on.
+//
+// The file must not be modified while it is mapped, and Close must be called
+// to unmap it once the MessageCatalog is no longer used.
+//
+// An error is returned if the file doesn't exist or can't be mapped,
+// or if the file is in an invalid format.
+func NewMappedMessageCatalog(filePath string, options ...Option) (*MessageCatalog, error) {
+	return newMessageCatalogFromStore(".mo", func() (store, error) {
+		f, err := po2json.OpenMOFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		return newMOStore(f), nil
+	}, options)
+}
+
+// newMessageCatalog creates a MessageCatalog from the catalog returned by load,
+// which reads a file of the specified extension.
+func newMessageCatalog(ext string, load func() (*po2json.Catalog, error), options []Option) (*MessageCatalog, error) {
+	return newMessageCatalogFromStore(ext, func() (store, error) {
+		catalog, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return catalogStore{catalog}, nil
+	}, options)
+}
 
+// newMessageCatalogFromStore creates a MessageCatalog from the store returned
+// by open, which reads a file of the specified extension.
+func newMessageCatalogFromStore(ext string, open func() (store, error), options []Option) (*MessageCatalog, error) {
+	mc := &MessageCatalog{}
+	mc.applyOptions(options)
+
+	s, err := open()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load .po file")
+		return nil, errors.Wrapf(err, "failed to load %s file", ext)
 	}
 
+	mc.mutex.Lock()
+	mc.store = s
+	mc.mutex.Unlock()
+
 	if err := mc.setPluralForms(); err != nil {
+		s.close()
 		return nil, errors.Wrap(err, "failed to set plural forms")
 	}
 
+	if mc.strict {
+		if err := mc.checkPluralCounts(); err != nil {
+			s.close()
+			return nil, errors.Wrap(err, "failed to check plural forms")
+		}
+	}
+
 	return mc, nil
 }
 
-// GetMessages returns a deep copy of the underlying data associated with the MessageCatalog.
+// Close releases the resources held by the MessageCatalog, such as the
+// mapping of a file opened with NewMappedMessageCatalog. The MessageCatalog
+// behaves as if it had not been loaded once it is closed.
+//
+// An error is returned if the MessageCatalog has not been loaded or has
+// already been closed.
+func (mc *MessageCatalog) Close() error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if mc.store == nil {
+		return ErrorNilMessageCatalog
+	}
+
+	err := mc.store.close()
+	mc.store = nil
+	return err
+}
+
+// GetMessages returns a deep copy of the underlying data associated with the
+// MessageCatalog keyed by msgctxt and then by msgid. This is the structure
+// that is returned by po2json.LoadBytes, except that lists are returned as
+// []interface{} as if the structure had been unmarshaled from JSON.
 //
-// An error is returned if the underlying data cannot be marshaled to JSON or
-// unmarshaled from JSON.
+// An error is returned if the MessageCatalog has not been loaded.
 func (mc *MessageCatalog) GetMessages() (map[string]interface{}, error) {
 	mc.mutex.RLock()
-	messagesBytes, err := json.Marshal(mc.messages)
-	mc.mutex.RUnlock()
+	defer mc.mutex.RUnlock()
 
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal message catalog")
+	if mc.store == nil {
+		return nil, ErrorNilMessageCatalog
 	}
 
-	messages := &map[string]interface{}{}
-	if err := json.Unmarshal(messagesBytes, messages); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal message catalog")
+	catalog, err := mc.store.load()
+	if err != nil {
+		return nil, err
 	}
 
-	return *messages, nil
+	messages := catalog.Map()
+	toInterfaceSlices(messages)
+	return messages, nil
 }
 
+// toInterfaceSlices replaces the []string values found anywhere in obj with
+// []interface{}, which is how GetMessages has always returned lists.
+func toInterfaceSlices(obj map[string]interface{}) {
+	for key, value := range obj {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			toInterfaceSlices(value)
+		case []string:
+			list := make([]interface{}, 0, len(value))
+			for _, item := range value {
+				list = append(list, item)
+			}
+			obj[key] = list
+		}
+	}
+}
+
+// setPluralForms compiles the plural expression of the Plural-Forms header,
+// so that it is only parsed once, and checks that it is in range of the
+// nplurals of the header. If there is no header, or only the placeholder of
+// a .pot file, the rule of the built-in table for the language is used, or
+// defaultPluralForms if there is none. Decimals are evaluated with the rule of decimalPluralRules for the language
+// if the header agrees with the built-in rule.
 func (mc *MessageCatalog) setPluralForms() error {
-	var err error
-	mc.pluralForms = defaultPluralForms
+	mc.pluralForms = defaultPluralExpr
+	mc.decimalPluralForms = defaultPluralExpr
+	mc.pluralRule = PluralRule{NPlurals: defaultNPlurals, Plural: defaultPluralForms, Source: PluralRuleDefault}
 
 	mc.mutex.RLock()
 	defer mc.mutex.RUnlock()
 
-	msgidMap, err := mc.getMsgidMap("", "")
-	if err != nil {
-		return err
+	if mc.store == nil {
+		return ErrorNilMessageCatalog
 	}
 
-	pluralFormsObj, ok := msgidMap["Plural-Forms"]
-	if !ok {
+	header := mc.store.header()
+	pluralForms, err := header.PluralForms()
+	if value, ok := header.Get(po2json.HeaderPluralForms); !ok || len(strings.TrimSpace(value)) == 0 || errors.Is(err, po2json.ErrPluralFormsPlaceholder) {
+		rule, ok := PluralRuleForLanguage(mc.pluralLanguage())
+		if !ok {
+			return nil
+		}
+
+		expr, err := pluralsparser.Compile(rule.Plural)
+		if err != nil {
+			return err
+		}
+
+		decimalExpr, err := decimalPluralExpr(mc.pluralLanguage(), expr)
+		if err != nil {
+			return err
+		}
+
+		mc.pluralForms = expr
+		mc.decimalPluralForms = decimalExpr
+		mc.pluralRule = rule
 		return nil
 	}
 
-	pluralFormsStr, ok := pluralFormsObj.(string)
-	if !ok {
-		return ErrorPluralsTypeAssertionFailed
+	if err != nil {
+		return err
 	}
 
-	matches := pluralFormsRegex.FindStringSubmatch(pluralFormsStr)
-	if matches == nil {
-		return nil
+	expr, err := pluralsparser.Compile(pluralForms.Plural)
+	if err != nil {
+		return err
 	}
 
-	mc.pluralForms = matches[1]
-	if _, err := pluralsparser.Evaluate(mc.pluralForms, 0); err != nil {
+	if err := checkPluralRange(expr, pluralForms.NPlurals); err != nil {
 		return err
 	}
 
+	// A header that agrees with the built-in rule for the language, as most
+	// do, only differs from it in that it can't describe decimals.
+	decimalExpr := expr
+	if rule, ok := PluralRuleForLanguage(mc.pluralLanguage()); ok && rule.NPlurals == pluralForms.NPlurals {
+		languageExpr, err := pluralsparser.Compile(rule.Plural)
+		if err != nil {
+			return err
+		}
+		if _, _, _, differ := pluralFormsDiffer(expr, languageExpr); !differ {
+			if decimalExpr, err = decimalPluralExpr(mc.pluralLanguage(), expr); err != nil {
+				return err
+			}
+		}
+	}
+
+	mc.pluralForms = expr
+	mc.decimalPluralForms = decimalExpr
+	mc.pluralRule = PluralRule{NPlurals: pluralForms.NPlurals, Plural: pluralForms.Plural, Source: PluralRuleHeader}
 	return nil
 }
 
-func (mc *MessageCatalog) getMsgidMap(msgctxt string, msgid string) (map[string]interface{}, error) {
+// decimalPluralExpr compiles the CLDR rule for decimals of language, or
+// returns expr if the integer rule of the language is also used for decimals.
+func decimalPluralExpr(language string, expr *pluralsparser.Expr) (*pluralsparser.Expr, error) {
+	plural, ok := decimalPluralRuleForLanguage(language)
+	if !ok {
+		return expr, nil
+	}
+	return pluralsparser.CompileCLDR(plural)
+}
+
+// pluralLanguage returns the language set with the Language option or,
+// failing that, the Language header. The caller must hold mc.mutex.
+func (mc *MessageCatalog) pluralLanguage() string {
+	if len(mc.language) > 0 {
+		return mc.language
+	}
+	return mc.store.header().Language()
+}
+
+// LintPluralForms compares the Plural-Forms header with the rule of the
+// built-in table for the language of the MessageCatalog, as returned by
+// PluralRuleForLanguage, and describes every way in which they disagree: a
+// different nplurals, or a plural expression that selects a different form
+// for some quantity. Expressions that are written differently but select the
+// same forms agree. No warnings are returned if there is no header or no rule
+// for the language.
+//
+// An error is returned if the MessageCatalog has not been loaded.
+func (mc *MessageCatalog) LintPluralForms() ([]string, error) {
 	mc.mutex.RLock()
 	defer mc.mutex.RUnlock()
 
-	if mc.messages == nil {
+	if mc.store == nil {
 		return nil, ErrorNilMessageCatalog
 	}
 
-	msgctxtObj, ok := mc.messages[msgctxt]
-	if !ok {
-		return nil, ErrorMsgctxtNotFound
+	if mc.pluralRule.Source != PluralRuleHeader {
+		return nil, nil
 	}
 
-	msgctxtMap, ok := msgctxtObj.(map[string]interface{})
+	language := mc.pluralLanguage()
+	expected, ok := PluralRuleForLanguage(language)
 	if !ok {
-		return nil, ErrorMsgctxtTypeAssertionFailed
+		return nil, nil
+	}
+
+	warnings := []string{}
+	if mc.pluralRule.NPlurals != expected.NPlurals {
+		warnings = append(warnings, fmt.Sprintf("nplurals=%d differs from nplurals=%d for %s", mc.pluralRule.NPlurals, expected.NPlurals, language))
+	}
+
+	expectedExpr, err := pluralsparser.Compile(expected.Plural)
+	if err != nil {
+		return nil, err
+	}
+
+	if n, idx, expectedIdx, ok := pluralFormsDiffer(mc.pluralForms, expectedExpr); ok {
+		warnings = append(warnings, fmt.Sprintf("plural=%s selects form %d for n = %d, but plural=%s for %s selects form %d", mc.pluralRule.Plural, idx, n, expected.Plural, language, expectedIdx))
+	}
+
+	return warnings, nil
+}
+
+// pluralFormsDiffer returns the first quantity up to pluralRangeLimit or of
+// pluralRangeQuantities for which expr and expected select different forms,
+// and these forms. false is returned if they select the same forms.
+func pluralFormsDiffer(expr *pluralsparser.Expr, expected *pluralsparser.Expr) (uint64, uint64, uint64, bool) {
+	check := func(n uint64) (uint64, uint64, bool) {
+		idx, _ := expr.Eval(n)
+		expectedIdx, _ := expected.Eval(n)
+		return idx, expectedIdx, idx != expectedIdx
+	}
+
+	for n := uint64(0); n <= pluralRangeLimit; n++ {
+		if idx, expectedIdx, ok := check(n); ok {
+			return n, idx, expectedIdx, true
+		}
+	}
+	for _, n := range pluralRangeQuantities {
+		if idx, expectedIdx, ok := check(n); ok {
+			return n, idx, expectedIdx, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// checkPluralRange evaluates expr for every quantity up to pluralRangeLimit
+// and for pluralRangeQuantities, and returns an error if expr can't be
+// evaluated or if it evaluates to an index that is not less than nplurals.
+//
+// Quantities for which a subtraction underflows are skipped. GNU gettext
+// wraps around instead, so such headers are valid there; the lookups for
+// these quantities return the *pluralsparser.UnderflowError instead.
+func checkPluralRange(expr *pluralsparser.Expr, nplurals int) error {
+	check := func(n uint64) error {
+		idx, err := expr.Eval(n)
+		var underflowErr *pluralsparser.UnderflowError
+		if errors.As(err, &underflowErr) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if idx >= uint64(nplurals) {
+			return errors.Wrapf(ErrorPluralFormsOutOfRange, "plural=%s evaluates to %d for n = %d with nplurals=%d", expr, idx, n, nplurals)
+		}
+		return nil
 	}
 
-	msgidObj, ok := msgctxtMap[msgid]
+	for n := uint64(0); n <= pluralRangeLimit; n++ {
+		if err := check(n); err != nil {
+			return err
+		}
+	}
+	for _, n := range pluralRangeQuantities {
+		if err := check(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPluralCounts returns ErrorPluralCountMismatch if any entry with a
+// msgid_plural does not have exactly nplurals plural translations.
+func (mc *MessageCatalog) checkPluralCounts() error {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	return mc.store.messages(func(msg *po2json.Message) error {
+		if len(msg.MsgidPlural) == 0 || len(msg.Plurals) == mc.pluralRule.NPlurals {
+			return nil
+		}
+
+		if msg.Position.Line > 0 {
+			return errors.Wrapf(ErrorPluralCountMismatch, "line %d: msgid %q has %d plural translations with nplurals=%d", msg.Position.Line, msg.Msgid, len(msg.Plurals), mc.pluralRule.NPlurals)
+		}
+		return errors.Wrapf(ErrorPluralCountMismatch, "msgid %q has %d plural translations with nplurals=%d", msg.Msgid, len(msg.Plurals), mc.pluralRule.NPlurals)
+	})
+}
+
+// getMessage returns the active message associated with the msgctxt and
+// msgid. The caller must hold mc.mutex.
+func (mc *MessageCatalog) getMessage(msgctxt string, msgid string) (*po2json.Message, error) {
+	if mc.store == nil {
+		return nil, ErrorNilMessageCatalog
+	}
+
+	msg, ok := mc.store.Lookup(msgctxt, msgid)
 	if !ok {
+		if !mc.store.HasContext(msgctxt) {
+			return nil, ErrorMsgctxtNotFound
+		}
 		return nil, ErrorMsgidNotFound
 	}
 
-	msgidMap, ok := msgidObj.(map[string]interface{})
-	if !ok {
-		return nil, ErrorMsgidTypeAssertionFailed
+	return msg, nil
+}
+
+// Entry contains the metadata associated with a single msgid in the
+// MessageCatalog.
+type Entry = po2json.Message
+
+// Previous contains the msgctxt, msgid, and msgid_plural that an entry had
+// before it was updated by msgmerge.
+type Previous = po2json.Previous
+
+// GetEntry returns the translations and metadata associated with the msgctxt
+// and msgid. Translations are returned even if the entry is marked as fuzzy.
+// The header is returned for an empty msgctxt and msgid.
+//
+// An error is returned if the msgid cannot be found.
+func (mc *MessageCatalog) GetEntry(msgctxt string, msgid string) (Entry, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	msg, err := mc.getMessage(msgctxt, msgid)
+	if err != nil {
+		return Entry{Msgctxt: msgctxt, Msgid: msgid}, err
+	}
+
+	return msg.Clone(), nil
+}
+
+// Header contains the header entry of the MessageCatalog and provides typed
+// access to its fields.
+type Header = po2json.Header
+
+// GetHeader returns a copy of the header of the MessageCatalog. The fields of
+// the header are kept in the order in which they appear in the .po file and
+// fields that have no typed accessor can be read with Header.Get.
+//
+// An error is returned if the MessageCatalog has not been loaded.
+func (mc *MessageCatalog) GetHeader() (Header, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	if mc.store == nil {
+		return Header{}, ErrorNilMessageCatalog
+	}
+
+	return mc.store.header().Clone(), nil
+}
+
+// PluralRuleSource identifies where the plural rule of a MessageCatalog came
+// from.
+type PluralRuleSource int
+
+const (
+	// PluralRuleDefault is the rule "nplurals=2; plural=n==1 ? 0 : 1;" that
+	// is used when no other rule is available.
+	PluralRuleDefault PluralRuleSource = iota
+	// PluralRuleHeader is the rule of the Plural-Forms header.
+	PluralRuleHeader
+	// PluralRuleLanguage is the rule of the built-in table for the language
+	// of the MessageCatalog, which is used when there is no Plural-Forms
+	// header.
+	PluralRuleLanguage
+)
+
+func (s PluralRuleSource) String() string {
+	switch s {
+	case PluralRuleDefault:
+		return "default"
+	case PluralRuleHeader:
+		return "header"
+	case PluralRuleLanguage:
+		return "language"
+	}
+	return "unknown"
+}
+
+// PluralRule is the plural rule that a MessageCatalog uses to select the
+// plural form of a translation.
+type PluralRule struct {
+	// NPlurals is the number of plural forms.
+	NPlurals int
+	// Plural is the C expression that selects the plural form for n.
+	Plural string
+	// Source is where the rule came from.
+	Source PluralRuleSource
+}
+
+// GetPluralRule returns the plural rule that the MessageCatalog uses, which
+// is the rule of the Plural-Forms header if it has one and otherwise the rule
+// that PluralRuleForLanguage returns for its language.
+//
+// An error is returned if the MessageCatalog has not been loaded.
+func (mc *MessageCatalog) GetPluralRule() (PluralRule, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	if mc.store == nil {
+		return PluralRule{}, ErrorNilMessageCatalog
+	}
+
+	return mc.pluralRule, nil
+}
+
+// GetObsoleteEntries returns the entries that were commented out with "#~"
+// in the order in which they appear in the .po file. Obsolete entries are
+// never used for translation.
+//
+// An error is returned if the MessageCatalog has not been loaded.
+func (mc *MessageCatalog) GetObsoleteEntries() ([]Entry, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	if mc.store == nil {
+		return nil, ErrorNilMessageCatalog
+	}
+
+	entries := []Entry{}
+	for _, msg := range mc.store.obsolete() {
+		entries = append(entries, msg.Clone())
+	}
+
+	return entries, nil
+}
+
+// WritePO writes the MessageCatalog to w in the .po format, including the
+// header, comments, and obsolete entries. Strings are wrapped at width columns
+// as GNU gettext does; use po2json.DefaultWidth for its default, or zero to
+// disable wrapping. The file is written in the charset of its Content-Type
+// header, which is the charset it was loaded from.
+//
+// An error is returned if the MessageCatalog has not been loaded, if a string
+// can't be represented in the charset, or if writing to w fails.
+func (mc *MessageCatalog) WritePO(w io.Writer, width int) error {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	if mc.store == nil {
+		return ErrorNilMessageCatalog
+	}
+
+	catalog, err := mc.store.load()
+	if err != nil {
+		return err
+	}
+
+	encoder := po2json.NewEncoder(w)
+	encoder.SetWidth(width)
+	return errors.Wrap(encoder.Encode(catalog), "failed to write .po file")
+}
+
+// WriteMO compiles the MessageCatalog into a little-endian .mo file with a hash
+// table, as msgfmt does, and writes it to w. Untranslated entries are omitted,
+// and so are fuzzy entries unless the MessageCatalog was loaded with UseFuzzy,
+// so that the .mo file serves the same translations as the MessageCatalog.
+//
+// An error is returned if the MessageCatalog has not been loaded or if
+// writing to w fails.
+func (mc *MessageCatalog) WriteMO(w io.Writer) error {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	if mc.store == nil {
+		return ErrorNilMessageCatalog
+	}
+
+	catalog, err := mc.store.load()
+	if err != nil {
+		return err
 	}
 
-	return msgidMap, nil
+	encoder := po2json.NewMOEncoder(w)
+	encoder.SetUseFuzzy(mc.useFuzzy)
+	return errors.Wrap(encoder.Encode(catalog), "failed to write .mo file")
+}
+
+// checkFuzzy returns ErrorFuzzyTranslation if the message is marked as fuzzy
+// and the MessageCatalog does not serve fuzzy translations.
+func (mc *MessageCatalog) checkFuzzy(msg *po2json.Message) error {
+	if !mc.useFuzzy && msg.HasFlag("fuzzy") {
+		return ErrorFuzzyTranslation
+	}
+
+	return nil
 }
 
 // Gettext returns the msgstr associated with the msgid.
@@ -237,9 +807,10 @@ func (mc *MessageCatalog) TryGettext(msgid string) (string, error) {
 // The specific plural returned is determined by evaluating the Plural-Forms
 // header given the specified quantity.
 //
-// The Plural-Forms falls back to plural=(n==1 ? 0 : 1) in the event that
-// no Plural-Forms header was provided in the .po file OR evaluation of the
-// plural failed.
+// If no Plural-Forms header was provided in the .po file, the built-in rule
+// for the language of the Language option or the Language header is used,
+// and plural=(n==1 ? 0 : 1) if there is no such rule. GetPluralRule returns
+// the rule that is used.
 //
 // In the case of plural evaluation failure or failure to find the associated
 // msgstr, msgidSingular is returned if quantity == 1, otherwise
@@ -255,9 +826,10 @@ func (mc *MessageCatalog) NGettext(msgidSingular string, msgidPlural string, qua
 // The specific plural returned is determined by evaluating the Plural-Forms
 // header given the specified quantity.
 //
-// The Plural-Forms falls back to plural=(n==1 ? 0 : 1) in the event that
-// no Plural-Forms header was provided in the .po file OR evaluation of the
-// plural failed.
+// If no Plural-Forms header was provided in the .po file, the built-in rule
+// for the language of the Language option or the Language header is used,
+// and plural=(n==1 ? 0 : 1) if there is no such rule. GetPluralRule returns
+// the rule that is used.
 //
 // In the case of plural evaluation failure or failure to find the associated
 // msgstr, msgidSingular is returned if quantity == 1, otherwise
@@ -266,6 +838,28 @@ func (mc *MessageCatalog) TryNGettext(msgidSingular string, msgidPlural string,
 	return mc.TryNPGettext("", msgidSingular, msgidPlural, quantity)
 }
 
+// NGettext64 is like NGettext but takes an int64 quantity.
+func (mc *MessageCatalog) NGettext64(msgidSingular string, msgidPlural string, quantity int64) string {
+	msgstr, _ := mc.TryNGettext64(msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNGettext64 is like TryNGettext but takes an int64 quantity.
+func (mc *MessageCatalog) TryNGettext64(msgidSingular string, msgidPlural string, quantity int64) (string, error) {
+	return mc.TryNPGettext64("", msgidSingular, msgidPlural, quantity)
+}
+
+// NGettextUint64 is like NGettext but takes a uint64 quantity.
+func (mc *MessageCatalog) NGettextUint64(msgidSingular string, msgidPlural string, quantity uint64) string {
+	msgstr, _ := mc.TryNGettextUint64(msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNGettextUint64 is like TryNGettext but takes a uint64 quantity.
+func (mc *MessageCatalog) TryNGettextUint64(msgidSingular string, msgidPlural string, quantity uint64) (string, error) {
+	return mc.TryNPGettextUint64("", msgidSingular, msgidPlural, quantity)
+}
+
 // PGettext returns the Particular msgstr associated with the msgctxt
 // and msgid.
 //
@@ -280,27 +874,26 @@ func (mc *MessageCatalog) PGettext(msgctxt string, msgid string) string {
 // and msgid.
 //
 // This method will return the msgid and an error if no corresponding msgstr
-// can be found.
+// can be found. Translations marked as fuzzy are only returned if the
+// MessageCatalog was loaded with UseFuzzy.
 func (mc *MessageCatalog) TryPGettext(msgctxt string, msgid string) (string, error) {
 	mc.mutex.RLock()
 	defer mc.mutex.RUnlock()
 
-	msgidMap, err := mc.getMsgidMap(msgctxt, msgid)
+	msg, err := mc.getMessage(msgctxt, msgid)
 	if err != nil {
 		return msgid, err
 	}
 
-	msgstrObj, ok := msgidMap["translation"]
-	if !ok {
-		return msgid, ErrorTranslationNotFound
+	if err := mc.checkFuzzy(msg); err != nil {
+		return msgid, err
 	}
 
-	msgstrStr, ok := msgstrObj.(string)
-	if !ok {
-		return msgid, ErrorTranslationTypeAssertionFailed
+	if len(msg.Translation) == 0 {
+		return msgid, ErrorTranslationNotFound
 	}
 
-	return msgstrStr, nil
+	return msg.Translation, nil
 }
 
 // NPGettext returns the Particular msgstr associate with the given msgctxt,
@@ -309,9 +902,10 @@ func (mc *MessageCatalog) TryPGettext(msgctxt string, msgid string) (string, err
 // The specific plural returned is determined by evaluating the Plural-Forms
 // header given the specified quantity.
 //
-// The Plural-Forms falls back to plural=(n==1 ? 0 : 1) in the event that
-// no Plural-Forms header was provided in the .po file OR evaluation of the
-// plural failed.
+// If no Plural-Forms header was provided in the .po file, the built-in rule
+// for the language of the Language option or the Language header is used,
+// and plural=(n==1 ? 0 : 1) if there is no such rule. GetPluralRule returns
+// the rule that is used.
 //
 // In the case of plural evaluation failure or failure to find the associated
 // msgstr, msgidSingular is returned if quantity == 1, otherwise
@@ -327,48 +921,192 @@ func (mc *MessageCatalog) NPGettext(msgctxt string, msgidSingular string, msgidP
 // The specific plural returned is determined by evaluating the Plural-Forms
 // header given the specified quantity.
 //
-// The Plural-Forms falls back to plural=(n==1 ? 0 : 1) in the event that
-// no Plural-Forms header was provided in the .po file OR evaluation of the
-// plural failed.
+// If no Plural-Forms header was provided in the .po file, the built-in rule
+// for the language of the Language option or the Language header is used,
+// and plural=(n==1 ? 0 : 1) if there is no such rule. GetPluralRule returns
+// the rule that is used.
 //
 // In the case of plural evaluation failure or failure to find the associated
 // msgstr, msgidSingular is returned if quantity == 1, otherwise
-// msgidPlural is returned. An error is also returned in these cases.
+// msgidPlural is returned. An error is also returned in these cases, which is
+// a *pluralsparser.DivisionByZeroError or a *pluralsparser.UnderflowError if
+// the Plural-Forms header can't be evaluated for the specified quantity. If
+// msgidPlural is empty, the msgid_plural stored in the MessageCatalog is
+// returned instead.
+//
+// A negative quantity is treated as its absolute value, so -1 selects the
+// same plural as 1.
+//
+// Translations marked as fuzzy are only returned if the MessageCatalog was
+// loaded with UseFuzzy.
 func (mc *MessageCatalog) TryNPGettext(msgctxt string, msgidSingular string, msgidPlural string, quantity int) (string, error) {
+	return mc.TryNPGettext64(msgctxt, msgidSingular, msgidPlural, int64(quantity))
+}
+
+// NPGettext64 is like NPGettext but takes an int64 quantity.
+func (mc *MessageCatalog) NPGettext64(msgctxt string, msgidSingular string, msgidPlural string, quantity int64) string {
+	msgstr, _ := mc.TryNPGettext64(msgctxt, msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNPGettext64 is like TryNPGettext but takes an int64 quantity.
+func (mc *MessageCatalog) TryNPGettext64(msgctxt string, msgidSingular string, msgidPlural string, quantity int64) (string, error) {
+	// Negating math.MinInt64 overflows back to math.MinInt64, whose
+	// conversion to uint64 is still its absolute value.
+	n := uint64(quantity)
+	if quantity < 0 {
+		n = uint64(-quantity)
+	}
+	return mc.TryNPGettextUint64(msgctxt, msgidSingular, msgidPlural, n)
+}
+
+// NPGettextUint64 is like NPGettext but takes a uint64 quantity.
+func (mc *MessageCatalog) NPGettextUint64(msgctxt string, msgidSingular string, msgidPlural string, quantity uint64) string {
+	msgstr, _ := mc.TryNPGettextUint64(msgctxt, msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNPGettextUint64 is like TryNPGettext but takes a uint64 quantity.
+func (mc *MessageCatalog) TryNPGettextUint64(msgctxt string, msgidSingular string, msgidPlural string, quantity uint64) (string, error) {
+	return mc.tryNPGettext(msgctxt, msgidSingular, msgidPlural, pluralsparser.Operands{I: quantity})
+}
+
+// NGettextDecimal is like NGettext but takes a decimal quantity. See
+// TryNPGettextDecimal.
+func (mc *MessageCatalog) NGettextDecimal(msgidSingular string, msgidPlural string, quantity string) string {
+	msgstr, _ := mc.TryNGettextDecimal(msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNGettextDecimal is like TryNGettext but takes a decimal quantity. See
+// TryNPGettextDecimal.
+func (mc *MessageCatalog) TryNGettextDecimal(msgidSingular string, msgidPlural string, quantity string) (string, error) {
+	return mc.TryNPGettextDecimal("", msgidSingular, msgidPlural, quantity)
+}
+
+// NPGettextDecimal is like NPGettext but takes a decimal quantity. See
+// TryNPGettextDecimal.
+func (mc *MessageCatalog) NPGettextDecimal(msgctxt string, msgidSingular string, msgidPlural string, quantity string) string {
+	msgstr, _ := mc.TryNPGettextDecimal(msgctxt, msgidSingular, msgidPlural, quantity)
+	return msgstr
+}
+
+// TryNPGettextDecimal is like TryNPGettext but takes the quantity as a
+// decimal number exactly as it is displayed, such as "1.5", "0.0" or "1.2e6"
+// for "1.2 million", so that the trailing zeros of the fraction can select
+// a different plural.
+//
+// Quantities with a fraction or an exponent are evaluated with the CLDR rule
+// for decimals of the language, which uses the CLDR plural operands described
+// by pluralsparser.ParseOperands, so that "1.0" is plural in English and
+// "1.5" is singular in French. This rule is used if the built-in rule for the
+// language is, and if the Plural-Forms header agrees with the built-in rule
+// as checked by LintPluralForms. Otherwise, the Plural-Forms expression is
+// evaluated with the exact value of the quantity as n, as described by
+// (*pluralsparser.Expr).EvalOperands, so that plural=n != 1 selects the
+// plural for "1.5".
+//
+// msgidSingular is only returned on failure if quantity is "1", since a
+// quantity such as "1.0" is plural in English. An error is also returned if
+// quantity is not a valid decimal number.
+func (mc *MessageCatalog) TryNPGettextDecimal(msgctxt string, msgidSingular string, msgidPlural string, quantity string) (string, error) {
+	ops, err := pluralsparser.ParseOperands(quantity)
+	if err != nil {
+		return msgidPlural, err
+	}
+	return mc.tryNPGettext(msgctxt, msgidSingular, msgidPlural, ops)
+}
+
+// NGettextFloat is like NGettext but takes a float64 quantity that is
+// displayed with precision fraction digits. See TryNPGettextFloat.
+func (mc *MessageCatalog) NGettextFloat(msgidSingular string, msgidPlural string, quantity float64, precision int) string {
+	msgstr, _ := mc.TryNGettextFloat(msgidSingular, msgidPlural, quantity, precision)
+	return msgstr
+}
+
+// TryNGettextFloat is like TryNGettext but takes a float64 quantity that is
+// displayed with precision fraction digits. See TryNPGettextFloat.
+func (mc *MessageCatalog) TryNGettextFloat(msgidSingular string, msgidPlural string, quantity float64, precision int) (string, error) {
+	return mc.TryNPGettextFloat("", msgidSingular, msgidPlural, quantity, precision)
+}
+
+// NPGettextFloat is like NPGettext but takes a float64 quantity that is
+// displayed with precision fraction digits. See TryNPGettextFloat.
+func (mc *MessageCatalog) NPGettextFloat(msgctxt string, msgidSingular string, msgidPlural string, quantity float64, precision int) string {
+	msgstr, _ := mc.TryNPGettextFloat(msgctxt, msgidSingular, msgidPlural, quantity, precision)
+	return msgstr
+}
+
+// TryNPGettextFloat is like TryNPGettextDecimal but takes a float64 quantity
+// that is displayed with precision fraction digits, as by
+// strconv.FormatFloat(quantity, 'f', precision, 64). A precision of -1 uses
+// the smallest number of digits that represents quantity exactly.
+//
+// An error that wraps ErrorInvalidQuantity is returned if quantity is not
+// finite, if its absolute value doesn't fit in a uint64, or if it is
+// displayed with more than 18 fraction digits.
+func (mc *MessageCatalog) TryNPGettextFloat(msgctxt string, msgidSingular string, msgidPlural string, quantity float64, precision int) (string, error) {
+	if math.IsNaN(quantity) || math.IsInf(quantity, 0) {
+		return msgidPlural, errors.Wrapf(ErrorInvalidQuantity, "%g is not finite", quantity)
+	}
+	// 2^64 is the smallest float64 that doesn't fit in a uint64.
+	if math.Abs(quantity) >= 1<<64 {
+		return msgidPlural, errors.Wrapf(ErrorInvalidQuantity, "%g exceeds %d", quantity, uint64(math.MaxUint64))
+	}
+	if precision > maxFractionDigits {
+		return msgidPlural, errors.Wrapf(ErrorInvalidQuantity, "precision %d exceeds %d", precision, maxFractionDigits)
+	}
+
+	decimal := strconv.FormatFloat(quantity, 'f', precision, 64)
+	if idx := strings.IndexByte(decimal, '.'); idx >= 0 && len(decimal)-idx-1 > maxFractionDigits {
+		return msgidPlural, errors.Wrapf(ErrorInvalidQuantity, "%g has more than %d fraction digits", quantity, maxFractionDigits)
+	}
+	return mc.TryNPGettextDecimal(msgctxt, msgidSingular, msgidPlural, decimal)
+}
+
+// tryNPGettext implements TryNPGettext for a quantity with the specified
+// plural operands.
+func (mc *MessageCatalog) tryNPGettext(msgctxt string, msgidSingular string, msgidPlural string, quantity pluralsparser.Operands) (string, error) {
 	mc.mutex.RLock()
 	defer mc.mutex.RUnlock()
 
+	isOne := quantity == pluralsparser.Operands{I: 1}
 	fallbackMsgstr := msgidSingular
-	if quantity != 1 {
+	if !isOne {
 		fallbackMsgstr = msgidPlural
 	}
 
-	idxUint, err := pluralsparser.Evaluate(mc.pluralForms, uint64(quantity))
+	msg, err := mc.getMessage(msgctxt, msgidSingular)
 	if err != nil {
 		return fallbackMsgstr, err
 	}
 
-	msgidMap, err := mc.getMsgidMap(msgctxt, msgidSingular)
+	pluralForms := mc.pluralForms
+	if quantity.V != 0 || quantity.E != 0 {
+		pluralForms = mc.decimalPluralForms
+	}
+	idxUint, err := pluralForms.EvalOperands(quantity)
 	if err != nil {
 		return fallbackMsgstr, err
 	}
 
-	msgstrPluralsObj, ok := msgidMap["plurals"]
-	if !ok {
-		return fallbackMsgstr, ErrorPluralNotFound
+	if !isOne && len(msgidPlural) == 0 {
+		fallbackMsgstr = msg.MsgidPlural
 	}
 
-	msgstrPluralsList, ok := msgstrPluralsObj.([]string)
-	if !ok {
-		return fallbackMsgstr, ErrorPluralsTypeAssertionFailed
+	if err := mc.checkFuzzy(msg); err != nil {
+		return fallbackMsgstr, err
 	}
 
-	idx := int(idxUint)
-	if idx >= len(msgstrPluralsList) {
+	if len(msg.Plurals) == 0 {
+		return fallbackMsgstr, ErrorPluralNotFound
+	}
+
+	if idxUint >= uint64(len(msg.Plurals)) {
 		return fallbackMsgstr, ErrorPluralsIndexOutOfBounds
 	}
 
-	return msgstrPluralsList[idx], nil
+	return msg.Plurals[idxUint], nil
 }
 
 // SearchResults contains the information required to retrieve a translation
@@ -383,29 +1121,39 @@ type SearchResults struct {
 //
 // A list of search results is returned if successful.
 //
-// An error in compiling the regular expression or and error in the structure of
-// the underlying data will result in nil search results and an error.
+// The header, whose msgid is empty, is searched first and the remaining
+// msgids are searched in the order in which they appear in the .po file.
+//
+// An error in compiling the regular expression or a MessageCatalog that has
+// not been loaded will result in nil search results and an error.
 func (mc *MessageCatalog) SearchMsgids(regex string) ([]SearchResults, error) {
 	re, err := regexp.Compile(regex)
 	if err != nil {
 		return nil, err
 	}
 
-	results := []SearchResults{}
-	for msgctxt, msgctxtObj := range mc.messages {
-		msgctxtMap, ok := msgctxtObj.(map[string]interface{})
-		if !ok {
-			return nil, ErrorMsgctxtTypeAssertionFailed
-		}
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
 
-		for msgid := range msgctxtMap {
-			if re.MatchString(msgid) {
-				results = append(results, SearchResults{
-					Msgctxt: msgctxt,
-					Msgid:   msgid,
-				})
-			}
+	if mc.store == nil {
+		return nil, ErrorNilMessageCatalog
+	}
+
+	results := []SearchResults{}
+	if re.MatchString("") {
+		results = append(results, SearchResults{})
+	}
+	err = mc.store.messages(func(msg *po2json.Message) error {
+		if re.MatchString(msg.Msgid) {
+			results = append(results, SearchResults{
+				Msgctxt: msg.Msgctxt,
+				Msgid:   msg.Msgid,
+			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return results, nil
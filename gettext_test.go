@@ -1,16 +1,42 @@
 package gogettext
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
-	"reflect"
+	"math"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/taylor-s-dean/gogettext/plurals-parser"
+	"github.com/taylor-s-dean/gogettext/po2json"
 )
 
 const (
 	poFilePath = "testdata/test.po"
+
+	// fuzzyFileContents is a .po file with fuzzy entries.
+	fuzzyFileContents = `
+msgid ""
+msgstr "Language: ru\n"
+
+#, fuzzy
+msgctxt "Farm"
+msgid "One piggy went to the market."
+msgstr "Одна свинья ушла на рынок."
+
+#, fuzzy, c-format
+msgid "%d pig"
+msgid_plural "%d pigs"
+msgstr[0] "%d свинья"
+msgstr[1] "%d свиньи"
+msgstr[2] "%d свиней"
+`
 )
 
 var messagesJSON = []byte(`
@@ -24,6 +50,7 @@ var messagesJSON = []byte(`
             "Plural-Forms": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"
         },
         "%d user likes this.": {
+            "msgidPlural": "%d users like this.",
             "plurals": [
                 "one",
                 "few",
@@ -40,11 +67,27 @@ var messagesJSON = []byte(`
     },
     "Button label": {
         "Log in": {
-            "translation": "Войти"
+            "extractedComments": [
+                "The label of the button that submits the login form."
+            ],
+            "references": [
+                "src/login.go:42",
+                "src/login.go:57",
+                "src/signup.go:12"
+            ],
+            "translation": "Войти",
+            "translatorComments": [
+                "Keep this short, the button is narrow."
+            ]
         }
     },
     "Context with plural": {
         "One piggy went to the market.": {
+            "msgidPlural": "One piggy went to the market.",
+            "previous": {
+                "msgctxt": "Context with plurals",
+                "msgid": "One pig went to the market."
+            },
             "plurals": [
                 "Одна свинья ушла на рынок.",
                 "%d свиньи пошли на рынок.",
@@ -70,6 +113,7 @@ var messagesJSON = []byte(`
 type TestSuite struct {
 	suite.Suite
 	mc       *MessageCatalog
+	mcFuzzy  *MessageCatalog
 	messages map[string]interface{}
 }
 
@@ -79,6 +123,10 @@ func (t *TestSuite) SetupSuite() {
 	t.NoError(err)
 	t.NotNil(t.mc)
 
+	t.mcFuzzy, err = NewMessageCatalogFromFile(poFilePath, UseFuzzy())
+	t.NoError(err)
+	t.NotNil(t.mcFuzzy)
+
 	err = json.Unmarshal(messagesJSON, &t.messages)
 	t.NoError(err)
 	t.NotNil(t.messages)
@@ -100,6 +148,190 @@ func (t *TestSuite) TestNewMessageCatalogFromFile_FileNotFound() {
 	t.Nil(mc)
 }
 
+func (t *TestSuite) TestNewMessageCatalogFromFile_ParseError() {
+	mc, err := NewMessageCatalogFromFile("testdata/invalid.po")
+	t.EqualError(err, "failed to load .po file: testdata/invalid.po:7:1: Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
+	t.Nil(mc)
+
+	var parseErr *po2json.ParseError
+	t.True(errors.As(err, &parseErr))
+	t.Equal(7, parseErr.Line)
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromReader_Valid() {
+	file, err := os.Open(poFilePath)
+	t.NoError(err)
+	defer file.Close()
+
+	mc, err := NewMessageCatalogFromReader(file)
+	t.NoError(err)
+	t.NotNil(mc)
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromReader_InvalidReader() {
+	mc, err := NewMessageCatalogFromReader(strings.NewReader(`
+msgid ""
+msgid ""
+`))
+	t.EqualError(err, "failed to load .po file: line 3, column 1: Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
+	t.Nil(mc)
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromFS_Valid() {
+	mc, err := NewMessageCatalogFromFS(os.DirFS("testdata"), "test.po", UseFuzzy())
+	t.NoError(err)
+	t.NotNil(mc)
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+	t.True(mc.useFuzzy)
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromFS_FileNotFound() {
+	mc, err := NewMessageCatalogFromFS(fstest.MapFS{}, "test.po")
+	t.EqualError(err, "failed to load .po file: open test.po: file does not exist")
+	t.Nil(mc)
+}
+
+func (t *TestSuite) TestNewMessageCatalogsFromFS_Valid() {
+	fileContents, err := ioutil.ReadFile(poFilePath)
+	t.NoError(err)
+
+	fsys := fstest.MapFS{
+		"locales/ru/messages.po":    &fstest.MapFile{Data: fileContents},
+		"locales/ru_UA/messages.po": &fstest.MapFile{Data: fileContents},
+		"locales/README":            &fstest.MapFile{Data: []byte("not a .po file")},
+		"other/messages.po":         &fstest.MapFile{Data: []byte("not a .po file")},
+	}
+
+	catalogs, err := NewMessageCatalogsFromFS(fsys, "locales")
+	t.NoError(err)
+	t.Len(catalogs, 2)
+	t.Equal("Войти", catalogs["locales/ru/messages.po"].PGettext("Button label", "Log in"))
+	t.Equal("Войти", catalogs["locales/ru_UA/messages.po"].PGettext("Button label", "Log in"))
+}
+
+func (t *TestSuite) TestNewMessageCatalogsFromFS_InvalidFile() {
+	fsys := fstest.MapFS{
+		"locales/ru.po": &fstest.MapFile{Data: []byte("msgstr \"Войти\"\n")},
+	}
+
+	catalogs, err := NewMessageCatalogsFromFS(fsys, "locales")
+	t.EqualError(err, "failed to load .po file: locales/ru.po:1:1: Invalid .po file. Found msgstr, expected one of {msgctxt, msgid}.")
+	t.Nil(catalogs)
+}
+
+func (t *TestSuite) TestNewMessageCatalogsFromFS_DirectoryNotFound() {
+	catalogs, err := NewMessageCatalogsFromFS(fstest.MapFS{}, "locales")
+	t.EqualError(err, "open locales: file does not exist")
+	t.Nil(catalogs)
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromMOFile_Valid() {
+	mc, err := NewMessageCatalogFromMOFile("testdata/canonical.mo")
+	t.Require().NoError(err)
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+	t.Equal("%d пользователям это нравится.", mc.NGettext("%d user likes this.", "%d users like this.", 5))
+
+	header, err := mc.GetHeader()
+	t.NoError(err)
+	t.Equal("ru", header.Language())
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromMOFile_FileNotFound() {
+	mc, err := NewMessageCatalogFromMOFile("./this/doesnt/exist")
+	t.Nil(mc)
+	t.EqualError(err, "failed to load .mo file: open ./this/doesnt/exist: no such file or directory")
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromMOFS_Valid() {
+	mc, err := NewMessageCatalogFromMOFS(os.DirFS("testdata"), "canonical_be.mo")
+	t.Require().NoError(err)
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromMOBytes_InvalidBytes() {
+	mc, err := NewMessageCatalogFromMOBytes([]byte("msgid \"\"\nmsgstr \"\"\n"))
+	t.Nil(mc)
+	t.EqualError(err, "failed to load .mo file: offset 0: Invalid .mo file. Found 19 bytes, expected at least 28.")
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromMOReader_Valid() {
+	file, err := os.Open("testdata/canonical.mo")
+	t.Require().NoError(err)
+	defer file.Close()
+
+	mc, err := NewMessageCatalogFromMOReader(file)
+	t.Require().NoError(err)
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+}
+
+func (t *TestSuite) TestNewMappedMessageCatalog_Valid() {
+	mc, err := NewMappedMessageCatalog("testdata/canonical.mo")
+	t.Require().NoError(err)
+	defer mc.Close()
+
+	t.Equal("Войти", mc.PGettext("Button label", "Log in"))
+	t.Equal("%d пользователю это нравится.", mc.NPGettext("", "%d user likes this.", "%d users like this.", 21))
+
+	_, err = mc.TryGettext("Remove the files")
+	t.EqualError(err, ErrorMsgidNotFound.Error())
+	_, err = mc.TryPGettext("Toolbar", "Log in")
+	t.EqualError(err, ErrorMsgctxtNotFound.Error())
+
+	header, err := mc.GetHeader()
+	t.NoError(err)
+	t.Equal("ru", header.Language())
+
+	loaded, err := NewMessageCatalogFromMOFile("testdata/canonical.mo")
+	t.Require().NoError(err)
+	expected, err := loaded.GetMessages()
+	t.Require().NoError(err)
+	messages, err := mc.GetMessages()
+	t.NoError(err)
+	t.Equal(expected, messages)
+
+	expectedResults, err := loaded.SearchMsgids("piggy|user")
+	t.Require().NoError(err)
+	results, err := mc.SearchMsgids("piggy|user")
+	t.NoError(err)
+	t.NotEmpty(results)
+	t.Equal(expectedResults, results)
+
+	obsolete, err := mc.GetObsoleteEntries()
+	t.NoError(err)
+	t.Empty(obsolete)
+
+	// The whole file is only parsed once.
+	catalog, err := mc.store.load()
+	t.Require().NoError(err)
+	again, err := mc.store.load()
+	t.Require().NoError(err)
+	t.Same(catalog, again)
+}
+
+func (t *TestSuite) TestNewMappedMessageCatalog_FileNotFound() {
+	mc, err := NewMappedMessageCatalog("./this/doesnt/exist")
+	t.Nil(mc)
+	t.EqualError(err, "failed to load .mo file: open ./this/doesnt/exist: no such file or directory")
+}
+
+func (t *TestSuite) TestNewMappedMessageCatalog_InvalidFile() {
+	mc, err := NewMappedMessageCatalog(poFilePath)
+	t.Nil(mc)
+	t.EqualError(err, "failed to load .mo file: testdata/test.po: offset 0: Invalid .mo file. Found magic number 0x6967736d, expected 0x950412de.")
+}
+
+func (t *TestSuite) TestMessageCatalog_Close() {
+	mc, err := NewMappedMessageCatalog("testdata/canonical.mo")
+	t.Require().NoError(err)
+	t.NoError(mc.Close())
+
+	msgstr, err := mc.TryGettext("Log in")
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
+	t.Equal("Log in", msgstr)
+	t.EqualError(mc.Close(), ErrorNilMessageCatalog.Error())
+}
+
 func (t *TestSuite) TestNewMessageCatalogFromString_Valid() {
 	fileContents, err := ioutil.ReadFile(poFilePath)
 	t.NoError(err)
@@ -127,6 +359,95 @@ msgstr ""
 	t.Nil(mc)
 }
 
+func (t *TestSuite) TestNewMessageCatalogFromString_PluralFormsDivisionByZero() {
+	mc, err := NewMessageCatalogFromString(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n % (3 - 3);\n"
+`)
+	t.EqualError(err, `failed to set plural forms: modulo by zero in "n % (3 - 3)"`)
+	t.Nil(mc)
+
+	var divErr *pluralsparser.DivisionByZeroError
+	t.True(errors.As(err, &divErr))
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromString_PluralFormsOutOfRange() {
+	for value, expected := range map[string]string{
+		"nplurals=2; plural=n;":              `failed to set plural forms: plural=n evaluates to 2 for n = 2 with nplurals=2: plural expression exceeds nplurals`,
+		"nplurals=0; plural=0;":              `failed to set plural forms: Plural-Forms: invalid nplurals "0" in "nplurals=0; plural=0;"`,
+		"nplurals=1; plural=n>100000;":       `failed to set plural forms: plural=n>100000 evaluates to 1 for n = 1000000 with nplurals=1: plural expression exceeds nplurals`,
+		"nplurals=2; plural=n % (n!=7) > 0;": `failed to set plural forms: modulo by zero in "n % (n!=7) > 0" for n = 7`,
+	} {
+		mc, err := NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Plural-Forms: " + value + "\\n\"\n")
+		t.EqualError(err, expected, value)
+		t.Nil(mc)
+	}
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromFile_StrictPlurals() {
+	mc, err := NewMessageCatalogFromFile(poFilePath, StrictPlurals())
+	t.EqualError(err, `failed to check plural forms: line 9: msgid "%d user likes this." has 4 plural translations with nplurals=3: plural count differs from nplurals`)
+	t.True(errors.Is(err, ErrorPluralCountMismatch))
+	t.Nil(mc)
+
+	mc, err = NewMessageCatalogFromFile("testdata/canonical.po", StrictPlurals())
+	t.NoError(err)
+	t.Equal(3, mc.pluralRule.NPlurals)
+
+	mc, err = NewMappedMessageCatalog("testdata/canonical.mo", StrictPlurals())
+	t.NoError(err)
+	t.NoError(mc.Close())
+
+	catalog, err := po2json.ParseString(`
+msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "File"
+msgid_plural "Files"
+msgstr[0] "Datei"
+msgstr[1] "Dateien"
+msgstr[2] "Dateien"
+`)
+	t.Require().NoError(err)
+	buf := bytes.Buffer{}
+	t.Require().NoError(po2json.NewMOEncoder(&buf).Encode(catalog))
+
+	mc, err = NewMessageCatalogFromMOBytes(buf.Bytes(), StrictPlurals())
+	t.EqualError(err, `failed to check plural forms: msgid "File" has 3 plural translations with nplurals=2: plural count differs from nplurals`)
+	t.Nil(mc)
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromString_PluralFormsSyntax() {
+	for _, value := range []string{
+		"nplurals=2; plural=n != 1",
+		"nplurals=2;\tplural=n != 1;",
+		"plural = (n > 1 ? 1 : 0) ; nplurals = 2",
+		"nplurals=2; plural=n*1 != 1/1;",
+	} {
+		mc, err := NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Plural-Forms: " + value + "\\n\"\n")
+		t.Require().NoError(err, value)
+
+		rule, err := mc.GetPluralRule()
+		t.NoError(err)
+		t.Equal(PluralRuleHeader, rule.Source, value)
+		t.Equal(2, rule.NPlurals, value)
+		t.Equal("one", mc.NGettext("one", "other", 1), value)
+		t.Equal("other", mc.NGettext("one", "other", 2), value)
+	}
+
+	for value, expected := range map[string]string{
+		"nplurals=2, plural=n != 1;":  `failed to set plural forms: Plural-Forms: invalid nplurals "2, plural=n != 1" in "nplurals=2, plural=n != 1;"`,
+		"nplurals=2; plural=n ≠ 1;":   "failed to set plural forms: parse error: syntax error: unexpected tokINVALID\nn ≠ 1\n  ^\n",
+		"nplurals=two; plural=n != 1": `failed to set plural forms: Plural-Forms: invalid nplurals "two" in "nplurals=two; plural=n != 1"`,
+		"nplurals=2; plural=v != 0;":  "failed to set plural forms: parse error: syntax error: unexpected tokINVALID, expecting tokIDENTIFIER or tokNUMBER or tokLPAREN\nv != 0\n^\n",
+	} {
+		mc, err := NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Plural-Forms: " + value + "\\n\"\n")
+		t.EqualError(err, expected, value)
+		t.Nil(mc)
+	}
+}
+
 func (t *TestSuite) TestNewMessageCatalogFromBytes_Valid() {
 	fileContents, err := ioutil.ReadFile(poFilePath)
 	t.NoError(err)
@@ -158,34 +479,48 @@ func (t *TestSuite) TestMessageCatalog_GetMessages_Valid() {
 	messages, err := t.mc.GetMessages()
 	t.NoError(err)
 	t.NotNil(messages)
-	t.True(reflect.DeepEqual(t.messages, messages))
+	t.Equal(t.messages, messages)
 }
 
 func (t *TestSuite) TestMessageCatalog_setPluralForms_Valid() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`
-{
-	"": {
-		"": {
-			"Plural-Forms": "nplurals=2; plural=(n==1 || n==11 ? 0 : 1);"
-		}
-	}
-}`), &mc.messages)
-	t.NoError(err)
-	t.NotNil(mc)
+	mc.store = catalogStore{&po2json.Catalog{Header: po2json.Header{Fields: []po2json.HeaderField{
+		{Key: "Plural-Forms", Value: "nplurals=2; plural=(n==1 || n==11 ? 0 : 1);"},
+	}}}}
 	err = mc.setPluralForms()
 	t.NoError(err)
-	t.Equal("(n==1 || n==11 ? 0 : 1)", mc.pluralForms)
+	t.Equal("(n==1 || n==11 ? 0 : 1)", mc.pluralForms.String())
+}
+
+func (t *TestSuite) TestMessageCatalog_GetPluralRule() {
+	rule, err := t.mc.GetPluralRule()
+	t.NoError(err)
+	t.Equal(PluralRule{
+		NPlurals: 3,
+		Plural:   "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)",
+		Source:   PluralRuleHeader,
+	}, rule)
+	t.Equal("header", rule.Source.String())
+
+	mc, err := NewMessageCatalogFromString("")
+	t.Require().NoError(err)
+	rule, err = mc.GetPluralRule()
+	t.NoError(err)
+	t.Equal(PluralRule{NPlurals: 2, Plural: "n==1 ? 0 : 1", Source: PluralRuleDefault}, rule)
+	t.Equal("default", rule.Source.String())
+
+	mc.store = nil
+	_, err = mc.GetPluralRule()
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
 }
 
 func (t *TestSuite) TestMessageCatalog_setPluralForms_NilMessageCatalog() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = nil
+	mc.store = nil
 	err = mc.setPluralForms()
 	t.EqualError(err, ErrorNilMessageCatalog.Error())
 }
@@ -194,101 +529,253 @@ func (t *TestSuite) TestMessageCatalog_setPluralForms_NoPluralForms() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"": {"": {"": ""}}}`), &mc.messages)
-	t.NoError(err)
-	t.NotNil(mc)
+	mc.store = catalogStore{&po2json.Catalog{Header: po2json.Header{Fields: []po2json.HeaderField{
+		{Key: "Language", Value: "ru"},
+	}}}}
 	err = mc.setPluralForms()
 	t.NoError(err)
-	t.Equal(mc.pluralForms, defaultPluralForms)
-}
+	t.Equal(pluralRules["ru"].Plural, mc.pluralForms.String())
+	t.Equal(PluralRuleLanguage, mc.pluralRule.Source)
 
-func (t *TestSuite) TestMessageCatalog_setPluralForms_PluralsTypeAssertionFailed() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
-	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"": {"": {"Plural-Forms": 2}}}`), &mc.messages)
-	t.NoError(err)
-	t.NotNil(mc)
+	mc.store = catalogStore{&po2json.Catalog{Header: po2json.Header{Fields: []po2json.HeaderField{
+		{Key: "Language", Value: "tlh"},
+	}}}}
 	err = mc.setPluralForms()
-	t.EqualError(err, ErrorPluralsTypeAssertionFailed.Error())
+	t.NoError(err)
+	t.Equal(defaultPluralForms, mc.pluralForms.String())
+	t.Equal(PluralRuleDefault, mc.pluralRule.Source)
 }
 
 func (t *TestSuite) TestMessageCatalog_setPluralForms_EmptyPluralFormsValue() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"": {"": {"Plural-Forms": ""}}}`), &mc.messages)
-	t.NoError(err)
-	t.NotNil(mc)
+	mc.store = catalogStore{&po2json.Catalog{Header: po2json.Header{Fields: []po2json.HeaderField{
+		{Key: "Plural-Forms", Value: ""},
+	}}}}
 	err = mc.setPluralForms()
-	t.Equal(mc.pluralForms, defaultPluralForms)
+	t.NoError(err)
+	t.Equal(defaultPluralForms, mc.pluralForms.String())
 }
 
 func (t *TestSuite) TestMessageCatalog_setPluralForms_InvalidPluralForms() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"": {"": {"Plural-Forms": "nplurals=2; plural=());"}}}`), &mc.messages)
-	t.NoError(err)
-	t.NotNil(mc)
+	mc.store = catalogStore{&po2json.Catalog{Header: po2json.Header{Fields: []po2json.HeaderField{
+		{Key: "Plural-Forms", Value: "nplurals=2; plural=());"},
+	}}}}
 	err = mc.setPluralForms()
 	t.Error(err)
 }
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_Valid() {
-	msgidMap, err := t.mc.getMsgidMap("", "")
+func (t *TestSuite) TestMessageCatalog_getMessage_Valid() {
+	msg, err := t.mc.getMessage("", "")
+	t.NoError(err)
+	t.Equal(&t.mc.store.header().Message, msg)
+
+	msg, err = t.mc.getMessage("Button label", "Log in")
 	t.NoError(err)
-	t.NotNil(msgidMap)
-	t.True(reflect.DeepEqual(t.messages[""].(map[string]interface{})[""].(map[string]interface{}), msgidMap))
+	t.Equal("Войти", msg.Translation)
 }
 
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_NilMessageCatalog() {
+func (t *TestSuite) TestMessageCatalog_getMessage_NilMessageCatalog() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = nil
-	msgidMap, err := mc.getMsgidMap("", "")
+	mc.store = nil
+	msg, err := mc.getMessage("", "")
 	t.EqualError(err, ErrorNilMessageCatalog.Error())
-	t.Nil(msgidMap)
+	t.Nil(msg)
 }
 
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_MsgctxtNotFound() {
-	msgidMap, err := t.mc.getMsgidMap("bob", "")
+func (t *TestSuite) TestMessageCatalog_getMessage_MsgctxtNotFound() {
+	msg, err := t.mc.getMessage("bob", "")
 	t.EqualError(err, ErrorMsgctxtNotFound.Error())
-	t.Nil(msgidMap)
+	t.Nil(msg)
+}
+
+func (t *TestSuite) TestMessageCatalog_getMessage_MsgidNotFound() {
+	msg, err := t.mc.getMessage("", "bob")
+	t.EqualError(err, ErrorMsgidNotFound.Error())
+	t.Nil(msg)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetEntry_Valid() {
+	entry, err := t.mc.GetEntry("Button label", "Log in")
+	t.NoError(err)
+	t.Equal(Entry{
+		Msgctxt:            "Button label",
+		Msgid:              "Log in",
+		Translation:        "Войти",
+		TranslatorComments: []string{"Keep this short, the button is narrow."},
+		ExtractedComments:  []string{"The label of the button that submits the login form."},
+		References:         []string{"src/login.go:42", "src/login.go:57", "src/signup.go:12"},
+		Position:           po2json.Position{Line: 27, Column: 1},
+	}, entry)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetEntry_NoComments() {
+	entry, err := t.mc.GetEntry("Dialog title", "Log in")
+	t.NoError(err)
+	t.Equal(Entry{
+		Msgctxt:     "Dialog title",
+		Msgid:       "Log in",
+		Translation: "Вход в систему",
+		Position:    po2json.Position{Line: 31, Column: 1},
+	}, entry)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetEntry_Flags() {
+	mc, err := NewMessageCatalogFromString(fuzzyFileContents)
+	t.Require().NoError(err)
+	entry, err := mc.GetEntry("", "%d pig")
+	t.NoError(err)
+	t.Equal([]string{"fuzzy", "c-format"}, entry.Flags)
+	t.True(entry.HasFlag("fuzzy"))
+	t.True(entry.HasFlag("c-format"))
+	t.False(entry.HasFlag("no-c-format"))
+
+	// The fuzzy flag of a duplicate definition doesn't apply to the first.
+	entry, err = t.mc.GetEntry("Context with plural", "One piggy went to the market.")
+	t.NoError(err)
+	t.Empty(entry.Flags)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetEntry_MsgidPlural() {
+	entry, err := t.mc.GetEntry("", "%d user likes this.")
+	t.NoError(err)
+	t.Equal("%d users like this.", entry.MsgidPlural)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetEntry_Previous() {
+	entry, err := t.mc.GetEntry("Context with plural", "One piggy went to the market.")
+	t.NoError(err)
+	t.Equal(Previous{
+		Msgctxt: "Context with plurals",
+		Msgid:   "One pig went to the market.",
+	}, entry.Previous)
 }
 
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_MsgidNotFound() {
-	msgidMap, err := t.mc.getMsgidMap("", "bob")
+func (t *TestSuite) TestMessageCatalog_GetEntry_MsgidNotFound() {
+	_, err := t.mc.GetEntry("Button label", "Log out")
 	t.EqualError(err, ErrorMsgidNotFound.Error())
-	t.Nil(msgidMap)
 }
 
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_MsgctxtTypeAssertionFailed() {
+func (t *TestSuite) TestMessageCatalog_GetHeader_Valid() {
+	header, err := t.mc.GetHeader()
+	t.NoError(err)
+	t.Equal("ru", header.Language())
+
+	contentType, ok := header.Get("Content-Type")
+	t.True(ok)
+	t.Equal("text/plain; charset=UTF-8", contentType)
+
+	pluralForms, err := header.PluralForms()
+	t.NoError(err)
+	t.Equal(3, pluralForms.NPlurals)
+
+	header.Fields[0].Value = "changed"
+	header, err = t.mc.GetHeader()
+	t.NoError(err)
+	t.Equal("1.0", header.Fields[0].Value)
+}
+
+func (t *TestSuite) TestMessageCatalog_GetHeader_NilMessageCatalog() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":""}`), &mc.messages)
+	mc.store = nil
+	_, err = mc.GetHeader()
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
+}
+
+func (t *TestSuite) TestMessageCatalog_GetObsoleteEntries_Valid() {
+	entries, err := t.mc.GetObsoleteEntries()
 	t.NoError(err)
-	msgidMap, err := mc.getMsgidMap("", "")
-	t.EqualError(err, ErrorMsgctxtTypeAssertionFailed.Error())
-	t.Nil(msgidMap)
+	t.Equal([]Entry{
+		{
+			Msgid:       "Log out",
+			Translation: "Выйти",
+			Position:    po2json.Position{Line: 61, Column: 4},
+		},
+		{
+			Msgctxt:     "Button label",
+			Msgid:       "Sign up",
+			Translation: "Регистрация",
+			Flags:       []string{"fuzzy"},
+			Previous:    Previous{Msgid: "Register"},
+			Position:    po2json.Position{Line: 66, Column: 4},
+		},
+	}, entries)
+}
+
+func (t *TestSuite) TestMessageCatalog_WritePO_Valid() {
+	fileContents, err := ioutil.ReadFile("testdata/canonical.po")
+	t.Require().NoError(err)
+	mc, err := NewMessageCatalogFromBytes(fileContents)
+	t.Require().NoError(err)
+
+	buf := bytes.Buffer{}
+	t.NoError(mc.WritePO(&buf, po2json.DefaultWidth))
+	t.Equal(string(fileContents), buf.String())
+}
+
+func (t *TestSuite) TestMessageCatalog_WritePO_Charset() {
+	fileContents := []byte("msgid \"\"\nmsgstr \"Content-Type: text/plain; charset=ISO-8859-1\\n\"\n\nmsgid \"coffee\"\nmsgstr \"caf\xe9\"\n")
+	mc, err := NewMessageCatalogFromBytes(fileContents)
+	t.Require().NoError(err)
+	t.Equal("café", mc.Gettext("coffee"))
+
+	buf := bytes.Buffer{}
+	t.NoError(mc.WritePO(&buf, po2json.DefaultWidth))
+	t.Equal(fileContents, buf.Bytes())
+
+	mc, err = NewMessageCatalogFromBytes(buf.Bytes())
+	t.Require().NoError(err)
+	t.Equal("café", mc.Gettext("coffee"))
 }
 
-func (t *TestSuite) TestMessageCatalog_getMsgidMap_MsgidTypeAssertionFailed() {
+func (t *TestSuite) TestMessageCatalog_WritePO_NilMessageCatalog() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"":""}}`), &mc.messages)
+	mc.store = nil
+	t.EqualError(mc.WritePO(&bytes.Buffer{}, po2json.DefaultWidth), ErrorNilMessageCatalog.Error())
+}
+
+func (t *TestSuite) TestMessageCatalog_WriteMO_Valid() {
+	mc, err := NewMessageCatalogFromFile("testdata/canonical.po")
+	t.Require().NoError(err)
+
+	expected, err := ioutil.ReadFile("testdata/canonical.mo")
+	t.Require().NoError(err)
+
+	buf := bytes.Buffer{}
+	t.NoError(mc.WriteMO(&buf))
+	t.Equal(expected, buf.Bytes())
+}
+
+func (t *TestSuite) TestMessageCatalog_WriteMO_UseFuzzy() {
+	mc, err := NewMessageCatalogFromFile("testdata/canonical.po", UseFuzzy())
+	t.Require().NoError(err)
+
+	buf := bytes.Buffer{}
+	t.NoError(mc.WriteMO(&buf))
+
+	moCatalog, err := NewMessageCatalogFromMOBytes(buf.Bytes())
+	t.Require().NoError(err)
+	t.Equal("Удалить файл", moCatalog.Gettext("Remove the files"))
+}
+
+func (t *TestSuite) TestMessageCatalog_WriteMO_NilMessageCatalog() {
+	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
-	msgidMap, err := mc.getMsgidMap("", "")
-	t.EqualError(err, ErrorMsgidTypeAssertionFailed.Error())
-	t.Nil(msgidMap)
+	mc.store = nil
+	t.EqualError(mc.WriteMO(&bytes.Buffer{}), ErrorNilMessageCatalog.Error())
+}
+
+func (t *TestSuite) TestMessageCatalog_TryGettext_Obsolete() {
+	msgstr, err := t.mc.TryGettext("Log out")
+	t.EqualError(err, ErrorMsgidNotFound.Error())
+	t.Equal("Log out", msgstr)
 }
 
 func (t *TestSuite) TestMessageCatalog_Gettext_Valid() {
@@ -307,26 +794,11 @@ func (t *TestSuite) TestMessageCatalog_TryGettext_TranslationNotFound() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"":{"":""}}}`), &mc.messages)
-	t.NoError(err)
 	msgstr, err := mc.TryGettext("")
 	t.EqualError(err, ErrorTranslationNotFound.Error())
 	t.Equal("", msgstr)
 }
 
-func (t *TestSuite) TestMessageCatalog_TryGettext_TranslationTypeAssertionFailed() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
-	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"":{"translation":2}}}`), &mc.messages)
-	t.NoError(err)
-	msgstr, err := mc.TryGettext("")
-	t.EqualError(err, ErrorTranslationTypeAssertionFailed.Error())
-	t.Equal("", msgstr)
-}
-
 func (t *TestSuite) TestMessageCatalog_TryGettext_WithStringEscapes() {
 	mc, err := NewMessageCatalogFromString(`
 msgid "test\"with quotes\"\nand a newline"
@@ -344,18 +816,258 @@ func (t *TestSuite) TestMessageCatalog_NGettext_Valid() {
 	t.Equal("few", msgstr)
 }
 
-func (t *TestSuite) TestMessageCatalog_TryNGettext_InvalidPluralForms() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
+func (t *TestSuite) TestMessageCatalog_NGettext_Negative() {
+	msgid := "%d user likes this."
+	t.Equal("one", t.mc.NGettext(msgid, "plural", -1))
+	t.Equal("few", t.mc.NGettext(msgid, "plural", -22))
+	t.Equal("many", t.mc.NGettext(msgid, "plural", -111))
+
+	t.Equal("singular", t.mc.NGettext("singular", "plural", -1))
+	t.Equal("plural", t.mc.NGettext("singular", "plural", -2))
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettext64() {
+	msgid := "%d user likes this."
+	for quantity, expected := range map[int64]string{
+		-21:           "one",
+		1e18 + 1:      "one",
+		-1e18 - 2:     "few",
+		math.MaxInt64: "many",
+		math.MinInt64: "many",
+	} {
+		msgstr, err := t.mc.TryNGettext64(msgid, "plural", quantity)
+		t.NoError(err, quantity)
+		t.Equal(expected, msgstr, quantity)
+		t.Equal(expected, t.mc.NGettext64(msgid, "plural", quantity), quantity)
+		t.Equal(expected, t.mc.NPGettext64("", msgid, "plural", quantity), quantity)
+	}
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettextUint64() {
+	msgid := "%d user likes this."
+	for quantity, expected := range map[uint64]string{
+		1:                   "one",
+		1e19 + 1:            "one",
+		1e19 + 3:            "few",
+		math.MaxUint64:      "many",
+		math.MaxUint64 - 13: "few",
+	} {
+		msgstr, err := t.mc.TryNGettextUint64(msgid, "plural", quantity)
+		t.NoError(err, quantity)
+		t.Equal(expected, msgstr, quantity)
+		t.Equal(expected, t.mc.NGettextUint64(msgid, "plural", quantity), quantity)
+		t.Equal(expected, t.mc.NPGettextUint64("", msgid, "plural", quantity), quantity)
+	}
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettextDecimal() {
+	const fileContents = `
+msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "%s hour"
+msgid_plural "%s hours"
+msgstr[0] "%s heure"
+msgstr[1] "%s heures"
+`
+
+	mc, err := NewMessageCatalogFromString(fileContents)
+	t.Require().NoError(err)
+	for quantity, expected := range map[string]string{
+		"1":    "%s heure",
+		"-1":   "%s heure",
+		"1.0":  "%s heure",
+		"1.5":  "%s heures",
+		"0.5":  "%s heures",
+		"0":    "%s heures",
+		"1e6":  "%s heures",
+		"11.0": "%s heures",
+	} {
+		msgstr, err := mc.TryNGettextDecimal("%s hour", "%s hours", quantity)
+		t.NoError(err, quantity)
+		t.Equal(expected, msgstr, quantity)
+		t.Equal(expected, mc.NGettextDecimal("%s hour", "%s hours", quantity), quantity)
+		t.Equal(expected, mc.NPGettextDecimal("", "%s hour", "%s hours", quantity), quantity)
+	}
+
+	msgstr, err := mc.TryNGettextDecimal("%s hour", "%s hours", "1,5")
+	t.EqualError(err, `invalid decimal "1,5"`)
+	t.Equal("%s hours", msgstr)
+
+	msgstr, err = mc.TryNGettextDecimal("%s minute", "%s minutes", "1")
+	t.EqualError(err, ErrorMsgidNotFound.Error())
+	t.Equal("%s minute", msgstr)
+
+	msgstr, err = mc.TryNGettextDecimal("%s minute", "%s minutes", "1.0")
+	t.EqualError(err, ErrorMsgidNotFound.Error())
+	t.Equal("%s minutes", msgstr)
+
+	// Without a Plural-Forms header, the built-in rule for French selects the
+	// singular for every quantity below 2.
+	mc, err = NewMessageCatalogFromString(strings.Replace(fileContents, "Plural-Forms: nplurals=2; plural=n != 1;", "Language: fr", 1))
+	t.Require().NoError(err)
+	for quantity, expected := range map[string]string{
+		"0":    "%s heure",
+		"1.0":  "%s heure",
+		"1.99": "%s heure",
+		"2.0":  "%s heures",
+	} {
+		t.Equal(expected, mc.NGettextDecimal("%s hour", "%s hours", quantity), quantity)
+	}
+
+	// A Plural-Forms header that agrees with the built-in rule for the
+	// language uses its rule for decimals too.
+	for header, tests := range map[string]map[string]string{
+		"Language: fr\\nPlural-Forms: nplurals=2; plural=(n > 1);": {
+			"1.5": "%s heure",
+			"2.0": "%s heures",
+		},
+		"Language: en\\nPlural-Forms: nplurals=2; plural=(n != 1);": {
+			"1":   "%s heure",
+			"1.0": "%s heures",
+		},
+		"Language: ru\\nPlural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);": {
+			"1.5": "%s heures",
+			"5":   "%s heures!",
+		},
+		// A header that disagrees keeps evaluating decimals as n.
+		"Language: fr\\nPlural-Forms: nplurals=2; plural=n != 1;": {
+			"1.5": "%s heures",
+			"1.0": "%s heure",
+		},
+	} {
+		mc, err = NewMessageCatalogFromString(strings.Replace(fileContents, "Plural-Forms: nplurals=2; plural=n != 1;", header, 1) + "msgstr[2] \"%s heures!\"\n")
+		t.Require().NoError(err, header)
+		for quantity, expected := range tests {
+			t.Equal(expected, mc.NGettextDecimal("%s hour", "%s hours", quantity), "%s for %s", quantity, header)
+		}
+	}
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettextFloat() {
+	mc, err := NewMessageCatalogFromString(`
+msgid ""
+msgstr "Language: en\n"
+
+msgid "%.1f km"
+msgid_plural "%.1f km"
+msgstr[0] "%.1f kilometer"
+msgstr[1] "%.1f kilometers"
+`)
+	t.Require().NoError(err)
+
+	for _, test := range []struct {
+		quantity  float64
+		precision int
+		expected  string
+	}{
+		{1, 0, "%.1f kilometer"},
+		{1, 1, "%.1f kilometers"},
+		{1, -1, "%.1f kilometer"},
+		{0.96, 0, "%.1f kilometer"},
+		{1.5, -1, "%.1f kilometers"},
+		{-1, 0, "%.1f kilometer"},
+		{0, 1, "%.1f kilometers"},
+	} {
+		msgstr, err := mc.TryNGettextFloat("%.1f km", "%.1f km", test.quantity, test.precision)
+		t.NoError(err, test.quantity)
+		t.Equal(test.expected, msgstr, "%v with precision %d", test.quantity, test.precision)
+		t.Equal(test.expected, mc.NGettextFloat("%.1f km", "%.1f km", test.quantity, test.precision))
+		t.Equal(test.expected, mc.NPGettextFloat("", "%.1f km", "%.1f km", test.quantity, test.precision))
+	}
+
+	for _, test := range []struct {
+		quantity  float64
+		precision int
+		expected  string
+	}{
+		{math.NaN(), 1, "NaN is not finite: invalid quantity"},
+		{math.Inf(-1), 1, "-Inf is not finite: invalid quantity"},
+		{1e300, 1, "1e+300 exceeds 18446744073709551615: invalid quantity"},
+		{-1 << 64, 0, "-1.8446744073709552e+19 exceeds 18446744073709551615: invalid quantity"},
+		{1.5, 19, "precision 19 exceeds 18: invalid quantity"},
+		{1e-300, -1, "1e-300 has more than 18 fraction digits: invalid quantity"},
+	} {
+		msgstr, err := mc.TryNGettextFloat("%.1f km", "%.1f km", test.quantity, test.precision)
+		t.EqualError(err, test.expected)
+		t.True(errors.Is(err, ErrorInvalidQuantity))
+		t.Equal("%.1f km", msgstr)
+	}
+
+	// The largest quantities and precisions that are in range are accepted.
+	for _, test := range []struct {
+		quantity  float64
+		precision int
+	}{
+		{math.Nextafter(1<<64, 0), 0},
+		{0.5, 18},
+		{1e-18, -1},
+	} {
+		_, err := mc.TryNGettextFloat("%.1f km", "%.1f km", test.quantity, test.precision)
+		t.NoError(err, "%g with precision %d", test.quantity, test.precision)
+	}
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettext_Underflow() {
+	mc, err := NewMessageCatalogFromString(`
+msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=n-7 > 0;\n"
+
+msgid "singular"
+msgid_plural "plural"
+msgstr[0] "few"
+msgstr[1] "many"
+`)
+	t.Require().NoError(err)
+
+	msgstr, err := mc.TryNGettext("singular", "plural", 7)
 	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"":{"Plural-Forms": "nplurals=1; plural=(();"}}}`), &mc.messages)
+	t.Equal("few", msgstr)
+
+	msgstr, err = mc.TryNGettext("singular", "plural", 8)
 	t.NoError(err)
+	t.Equal("many", msgstr)
+
+	msgstr, err = mc.TryNGettext("singular", "plural", 3)
+	t.EqualError(err, `3 - 7 underflows in "n-7 > 0" for n = 3`)
+	t.Equal("plural", msgstr)
+
+	var underflowErr *pluralsparser.UnderflowError
+	t.True(errors.As(err, &underflowErr))
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettext_DivisionByZero() {
+	mc, err := NewMessageCatalogFromString(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n / (n != 5000) % 2;\n"
+
+msgid "singular"
+msgid_plural "plural"
+msgstr[0] "even"
+msgstr[1] "odd"
+`)
+	t.Require().NoError(err)
+
+	msgstr, err := mc.TryNGettext("singular", "plural", 3)
+	t.NoError(err)
+	t.Equal("odd", msgstr)
+
+	msgstr, err = mc.TryNGettext("singular", "plural", 5000)
+	t.EqualError(err, `division by zero in "n / (n != 5000) % 2" for n = 5000`)
+	t.Equal("plural", msgstr)
+
+	var divErr *pluralsparser.DivisionByZeroError
+	t.True(errors.As(err, &divErr))
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNGettext_NilMessageCatalog() {
+	mc := &MessageCatalog{}
 	msgstr, err := mc.TryNGettext("singular", "plural", 1)
-	t.Error(err)
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
 	t.Equal("singular", msgstr)
 	msgstr, err = mc.TryNGettext("singular", "plural", 2)
-	t.Error(err)
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
 	t.Equal("plural", msgstr)
 }
 
@@ -369,18 +1081,6 @@ func (t *TestSuite) TestMessageCatalog_TryNGettext_PluralNotFound() {
 	t.Equal("plural", msgstr)
 }
 
-func (t *TestSuite) TestMessageCatalog_TryNGettext_PluralsTypeAssertionFailed() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
-	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"singular":{"plurals":1}}}`), &mc.messages)
-	t.NoError(err)
-	msgstr, err := mc.TryNGettext("singular", "plural", 1)
-	t.EqualError(err, ErrorPluralsTypeAssertionFailed.Error())
-	t.Equal("singular", msgstr)
-}
-
 func (t *TestSuite) TestMessageCatalog_TryNGettext_PluralIndexOutOfBounds() {
 	mc, err := NewMessageCatalogFromString(`
 msgid ""
@@ -414,52 +1114,111 @@ func (t *TestSuite) TestMessageCatalog_TryPGettext_MsgctxtNotFound() {
 }
 
 func (t *TestSuite) TestMessageCatalog_TryPGettext_TranslationNotFound() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
+	mc, err := NewMessageCatalogFromBytes([]byte(`
+msgid "test"
+msgstr ""
+`))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"test":{}}}`), &mc.messages)
-	t.NoError(err)
 	msgstr, err := mc.TryPGettext("", "test")
 	t.EqualError(err, ErrorTranslationNotFound.Error())
 	t.Equal("test", msgstr)
 }
 
-func (t *TestSuite) TestMessageCatalog_TryPGettext_TranslationTypeAssertionFailed() {
-	mc, err := NewMessageCatalogFromBytes([]byte(""))
+func (t *TestSuite) TestMessageCatalog_TryPGettext_Fuzzy() {
+	mc, err := NewMessageCatalogFromString(fuzzyFileContents)
+	t.Require().NoError(err)
+	msgstr, err := mc.TryPGettext("Farm", "One piggy went to the market.")
+	t.EqualError(err, ErrorFuzzyTranslation.Error())
+	t.Equal("One piggy went to the market.", msgstr)
+
+	mc, err = NewMessageCatalogFromString(fuzzyFileContents, UseFuzzy())
+	t.Require().NoError(err)
+	msgstr, err = mc.TryPGettext("Farm", "One piggy went to the market.")
 	t.NoError(err)
-	t.NotNil(mc)
-	mc.messages = map[string]interface{}{}
-	err = json.Unmarshal([]byte(`{"":{"test":{"translation":1}}}`), &mc.messages)
+	t.Equal("Одна свинья ушла на рынок.", msgstr)
+
+	// The duplicate definition in the test file is fuzzy, but the first one
+	// is not.
+	msgstr, err = t.mc.TryPGettext("Context with plural", "One piggy went to the market.")
 	t.NoError(err)
-	msgstr, err := mc.TryPGettext("", "test")
-	t.EqualError(err, ErrorTranslationTypeAssertionFailed.Error())
-	t.Equal("test", msgstr)
+	t.Equal("Одна свинья ушла на рынок.", msgstr)
 }
 
 func (t *TestSuite) TestMessageCatalog_NPGettext_Valid_One() {
-	msgstr := t.mc.NPGettext("Context with plural", "One piggy went to the market.", "", 1)
+	msgstr := t.mcFuzzy.NPGettext("Context with plural", "One piggy went to the market.", "", 1)
 	t.Equal("Одна свинья ушла на рынок.", msgstr)
 }
 
+func (t *TestSuite) TestMessageCatalog_NPGettext_Concurrent() {
+	expected := map[int]string{1: "one", 2: "few", 5: "many", 21: "one", 22: "few", 111: "many"}
+
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := 0; idx < 100; idx++ {
+				for quantity, msgstr := range expected {
+					t.Equal(msgstr, t.mc.NPGettext("", "%d user likes this.", "%d users like this.", quantity))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func (t *TestSuite) TestMessageCatalog_TryNPGettext_Valid_One() {
-	msgstr, err := t.mc.TryNPGettext("Context with plural", "One piggy went to the market.", "", 1)
+	msgstr, err := t.mcFuzzy.TryNPGettext("Context with plural", "One piggy went to the market.", "", 1)
 	t.NoError(err)
 	t.Equal("Одна свинья ушла на рынок.", msgstr)
 }
 
 func (t *TestSuite) TestMessageCatalog_TryNPGettext_Valid_Few() {
-	msgstr, err := t.mc.TryNPGettext("Context with plural", "One piggy went to the market.", "", 2)
+	msgstr, err := t.mcFuzzy.TryNPGettext("Context with plural", "One piggy went to the market.", "", 2)
 	t.NoError(err)
 	t.Equal("%d свиньи пошли на рынок.", msgstr)
 }
 
 func (t *TestSuite) TestMessageCatalog_TryNPGettext_Valid_Many() {
-	msgstr, err := t.mc.TryNPGettext("Context with plural", "One piggy went to the market.", "", 5)
+	msgstr, err := t.mcFuzzy.TryNPGettext("Context with plural", "One piggy went to the market.", "", 5)
 	t.NoError(err)
 	t.Equal("На рынок вышли %d поросят.", msgstr)
 }
 
+func (t *TestSuite) TestMessageCatalog_TryNPGettext_Fuzzy() {
+	mc, err := NewMessageCatalogFromString(fuzzyFileContents)
+	t.Require().NoError(err)
+	msgstr, err := mc.TryNPGettext("", "%d pig", "plural", 1)
+	t.EqualError(err, ErrorFuzzyTranslation.Error())
+	t.Equal("%d pig", msgstr)
+	msgstr, err = mc.TryNPGettext("", "%d pig", "plural", 2)
+	t.EqualError(err, ErrorFuzzyTranslation.Error())
+	t.Equal("plural", msgstr)
+
+	mc, err = NewMessageCatalogFromString(fuzzyFileContents, UseFuzzy())
+	t.Require().NoError(err)
+	msgstr, err = mc.TryNPGettext("", "%d pig", "plural", 2)
+	t.NoError(err)
+	t.Equal("%d свиньи", msgstr)
+}
+
+func (t *TestSuite) TestMessageCatalog_TryNPGettext_CatalogMsgidPluralFallback() {
+	mc, err := NewMessageCatalogFromString(`
+msgid "%d apple"
+msgid_plural "%d apples"
+msgstr[0] ""
+`)
+	t.NoError(err)
+	t.NotNil(mc)
+	msgstr, err := mc.TryNGettext("%d apple", "", 2)
+	t.EqualError(err, ErrorPluralsIndexOutOfBounds.Error())
+	t.Equal("%d apples", msgstr)
+	msgstr, err = mc.TryNGettext("%d apple", "%d pommes", 2)
+	t.EqualError(err, ErrorPluralsIndexOutOfBounds.Error())
+	t.Equal("%d pommes", msgstr)
+}
+
 func (t *TestSuite) TestMessageCatalog_TryNPGettext_Singular_MsgctxtNotFound() {
 	msgstr, err := t.mc.TryNPGettext("this doesnt exist", "singular", "plural", 1)
 	t.EqualError(err, ErrorMsgctxtNotFound.Error())
@@ -498,13 +1257,13 @@ msgstr "place of birth"
 	})
 }
 
-func (t *TestSuite) TestMessageCatalog_SearchMsgids_MsgctxtTypeAssertionFailed() {
+func (t *TestSuite) TestMessageCatalog_SearchMsgids_NilMessageCatalog() {
 	mc, err := NewMessageCatalogFromBytes([]byte(""))
 	t.NoError(err)
 	t.NotNil(mc)
-	mc.messages = map[string]interface{}{"": ""}
+	mc.store = nil
 	results, err := mc.SearchMsgids(`braze\.1234\.[a-zA-Z0-9_-]`)
-	t.EqualError(err, ErrorMsgctxtTypeAssertionFailed.Error())
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
 	t.Nil(results)
 }
 
@@ -513,3 +1272,15 @@ func (t *TestSuite) TestMessageCatalog_SearchMsgids_InvalidRegex() {
 	t.EqualError(err, "error parsing regexp: missing argument to repetition operator: `*`")
 	t.Nil(results)
 }
+
+func BenchmarkMessageCatalog_NPGettext(b *testing.B) {
+	mc, err := NewMessageCatalogFromFile(poFilePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for idx := 0; idx < b.N; idx++ {
+		mc.NPGettext("", "%d user likes this.", "%d users like this.", idx)
+	}
+}
@@ -1,8 +1,9 @@
 module github.com/taylor-s-dean/gogettext
 
-go 1.14
+go 1.16
 
 require (
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/text v0.13.0
 )
@@ -0,0 +1,45 @@
+package gogettext
+
+// Option configures how a MessageCatalog is loaded and served.
+type Option func(*MessageCatalog)
+
+// UseFuzzy makes the MessageCatalog serve translations that are marked with
+// the "fuzzy" flag.
+//
+// By default fuzzy translations are treated as missing, which mirrors the
+// behavior of GNU gettext and msgfmt without --use-fuzzy.
+func UseFuzzy() Option {
+	return func(mc *MessageCatalog) {
+		mc.useFuzzy = true
+	}
+}
+
+// StrictPlurals makes loading the MessageCatalog fail with
+// ErrorPluralCountMismatch if any entry with a msgid_plural does not have
+// exactly as many msgstr[N] translations as the nplurals of the Plural-Forms
+// header, as msgfmt --check does.
+//
+// By default such entries are loaded, and looking up a plural that they lack
+// returns ErrorPluralsIndexOutOfBounds.
+func StrictPlurals() Option {
+	return func(mc *MessageCatalog) {
+		mc.strict = true
+	}
+}
+
+// Language sets the locale, such as "ru" or "pt_BR", whose plural rule is
+// looked up with PluralRuleForLanguage when the file has no Plural-Forms
+// header, and against which LintPluralForms checks the header.
+//
+// By default the Language header of the file is used.
+func Language(locale string) Option {
+	return func(mc *MessageCatalog) {
+		mc.language = locale
+	}
+}
+
+func (mc *MessageCatalog) applyOptions(options []Option) {
+	for _, option := range options {
+		option(mc)
+	}
+}
@@ -0,0 +1,370 @@
+package gogettext
+
+import (
+	"strings"
+)
+
+// pluralRules contains the gettext Plural-Forms of the CLDR cardinal plural
+// rules keyed by language. Only the integer rules are translated, so the
+// "many" category of compact decimals such as "1 M" in Spanish or French is
+// omitted, which keeps the rules identical to those written by msginit.
+var pluralRules = map[string]PluralRule{
+	// One form.
+	"bm":  {NPlurals: 1, Plural: "0"},
+	"bo":  {NPlurals: 1, Plural: "0"},
+	"dz":  {NPlurals: 1, Plural: "0"},
+	"hnj": {NPlurals: 1, Plural: "0"},
+	"id":  {NPlurals: 1, Plural: "0"},
+	"ig":  {NPlurals: 1, Plural: "0"},
+	"ii":  {NPlurals: 1, Plural: "0"},
+	"ja":  {NPlurals: 1, Plural: "0"},
+	"jbo": {NPlurals: 1, Plural: "0"},
+	"jv":  {NPlurals: 1, Plural: "0"},
+	"kde": {NPlurals: 1, Plural: "0"},
+	"kea": {NPlurals: 1, Plural: "0"},
+	"km":  {NPlurals: 1, Plural: "0"},
+	"ko":  {NPlurals: 1, Plural: "0"},
+	"lkt": {NPlurals: 1, Plural: "0"},
+	"lo":  {NPlurals: 1, Plural: "0"},
+	"ms":  {NPlurals: 1, Plural: "0"},
+	"my":  {NPlurals: 1, Plural: "0"},
+	"nqo": {NPlurals: 1, Plural: "0"},
+	"osa": {NPlurals: 1, Plural: "0"},
+	"sah": {NPlurals: 1, Plural: "0"},
+	"ses": {NPlurals: 1, Plural: "0"},
+	"sg":  {NPlurals: 1, Plural: "0"},
+	"su":  {NPlurals: 1, Plural: "0"},
+	"th":  {NPlurals: 1, Plural: "0"},
+	"to":  {NPlurals: 1, Plural: "0"},
+	"tpi": {NPlurals: 1, Plural: "0"},
+	"vi":  {NPlurals: 1, Plural: "0"},
+	"wo":  {NPlurals: 1, Plural: "0"},
+	"yo":  {NPlurals: 1, Plural: "0"},
+	"yue": {NPlurals: 1, Plural: "0"},
+	"zh":  {NPlurals: 1, Plural: "0"},
+
+	// One form for 1 and another for everything else.
+	"af":    {NPlurals: 2, Plural: "n != 1"},
+	"an":    {NPlurals: 2, Plural: "n != 1"},
+	"asa":   {NPlurals: 2, Plural: "n != 1"},
+	"ast":   {NPlurals: 2, Plural: "n != 1"},
+	"az":    {NPlurals: 2, Plural: "n != 1"},
+	"bal":   {NPlurals: 2, Plural: "n != 1"},
+	"bem":   {NPlurals: 2, Plural: "n != 1"},
+	"bez":   {NPlurals: 2, Plural: "n != 1"},
+	"bg":    {NPlurals: 2, Plural: "n != 1"},
+	"brx":   {NPlurals: 2, Plural: "n != 1"},
+	"ca":    {NPlurals: 2, Plural: "n != 1"},
+	"ce":    {NPlurals: 2, Plural: "n != 1"},
+	"cgg":   {NPlurals: 2, Plural: "n != 1"},
+	"chr":   {NPlurals: 2, Plural: "n != 1"},
+	"ckb":   {NPlurals: 2, Plural: "n != 1"},
+	"da":    {NPlurals: 2, Plural: "n != 1"},
+	"de":    {NPlurals: 2, Plural: "n != 1"},
+	"dv":    {NPlurals: 2, Plural: "n != 1"},
+	"ee":    {NPlurals: 2, Plural: "n != 1"},
+	"el":    {NPlurals: 2, Plural: "n != 1"},
+	"en":    {NPlurals: 2, Plural: "n != 1"},
+	"eo":    {NPlurals: 2, Plural: "n != 1"},
+	"es":    {NPlurals: 2, Plural: "n != 1"},
+	"et":    {NPlurals: 2, Plural: "n != 1"},
+	"eu":    {NPlurals: 2, Plural: "n != 1"},
+	"fi":    {NPlurals: 2, Plural: "n != 1"},
+	"fo":    {NPlurals: 2, Plural: "n != 1"},
+	"fur":   {NPlurals: 2, Plural: "n != 1"},
+	"fy":    {NPlurals: 2, Plural: "n != 1"},
+	"gl":    {NPlurals: 2, Plural: "n != 1"},
+	"gsw":   {NPlurals: 2, Plural: "n != 1"},
+	"ha":    {NPlurals: 2, Plural: "n != 1"},
+	"haw":   {NPlurals: 2, Plural: "n != 1"},
+	"hu":    {NPlurals: 2, Plural: "n != 1"},
+	"ia":    {NPlurals: 2, Plural: "n != 1"},
+	"io":    {NPlurals: 2, Plural: "n != 1"},
+	"it":    {NPlurals: 2, Plural: "n != 1"},
+	"jgo":   {NPlurals: 2, Plural: "n != 1"},
+	"jmc":   {NPlurals: 2, Plural: "n != 1"},
+	"ka":    {NPlurals: 2, Plural: "n != 1"},
+	"kaj":   {NPlurals: 2, Plural: "n != 1"},
+	"kcg":   {NPlurals: 2, Plural: "n != 1"},
+	"kk":    {NPlurals: 2, Plural: "n != 1"},
+	"kkj":   {NPlurals: 2, Plural: "n != 1"},
+	"kl":    {NPlurals: 2, Plural: "n != 1"},
+	"ks":    {NPlurals: 2, Plural: "n != 1"},
+	"ksb":   {NPlurals: 2, Plural: "n != 1"},
+	"ku":    {NPlurals: 2, Plural: "n != 1"},
+	"ky":    {NPlurals: 2, Plural: "n != 1"},
+	"lb":    {NPlurals: 2, Plural: "n != 1"},
+	"lg":    {NPlurals: 2, Plural: "n != 1"},
+	"lij":   {NPlurals: 2, Plural: "n != 1"},
+	"mas":   {NPlurals: 2, Plural: "n != 1"},
+	"mgo":   {NPlurals: 2, Plural: "n != 1"},
+	"ml":    {NPlurals: 2, Plural: "n != 1"},
+	"mn":    {NPlurals: 2, Plural: "n != 1"},
+	"mr":    {NPlurals: 2, Plural: "n != 1"},
+	"nah":   {NPlurals: 2, Plural: "n != 1"},
+	"nb":    {NPlurals: 2, Plural: "n != 1"},
+	"nd":    {NPlurals: 2, Plural: "n != 1"},
+	"ne":    {NPlurals: 2, Plural: "n != 1"},
+	"nl":    {NPlurals: 2, Plural: "n != 1"},
+	"nn":    {NPlurals: 2, Plural: "n != 1"},
+	"nnh":   {NPlurals: 2, Plural: "n != 1"},
+	"no":    {NPlurals: 2, Plural: "n != 1"},
+	"nr":    {NPlurals: 2, Plural: "n != 1"},
+	"ny":    {NPlurals: 2, Plural: "n != 1"},
+	"nyn":   {NPlurals: 2, Plural: "n != 1"},
+	"om":    {NPlurals: 2, Plural: "n != 1"},
+	"or":    {NPlurals: 2, Plural: "n != 1"},
+	"os":    {NPlurals: 2, Plural: "n != 1"},
+	"pap":   {NPlurals: 2, Plural: "n != 1"},
+	"ps":    {NPlurals: 2, Plural: "n != 1"},
+	"pt_PT": {NPlurals: 2, Plural: "n != 1"},
+	"rm":    {NPlurals: 2, Plural: "n != 1"},
+	"rof":   {NPlurals: 2, Plural: "n != 1"},
+	"rwk":   {NPlurals: 2, Plural: "n != 1"},
+	"saq":   {NPlurals: 2, Plural: "n != 1"},
+	"sc":    {NPlurals: 2, Plural: "n != 1"},
+	"sd":    {NPlurals: 2, Plural: "n != 1"},
+	"sdh":   {NPlurals: 2, Plural: "n != 1"},
+	"seh":   {NPlurals: 2, Plural: "n != 1"},
+	"sn":    {NPlurals: 2, Plural: "n != 1"},
+	"so":    {NPlurals: 2, Plural: "n != 1"},
+	"sq":    {NPlurals: 2, Plural: "n != 1"},
+	"ss":    {NPlurals: 2, Plural: "n != 1"},
+	"ssy":   {NPlurals: 2, Plural: "n != 1"},
+	"st":    {NPlurals: 2, Plural: "n != 1"},
+	"sv":    {NPlurals: 2, Plural: "n != 1"},
+	"sw":    {NPlurals: 2, Plural: "n != 1"},
+	"syr":   {NPlurals: 2, Plural: "n != 1"},
+	"ta":    {NPlurals: 2, Plural: "n != 1"},
+	"te":    {NPlurals: 2, Plural: "n != 1"},
+	"teo":   {NPlurals: 2, Plural: "n != 1"},
+	"tig":   {NPlurals: 2, Plural: "n != 1"},
+	"tk":    {NPlurals: 2, Plural: "n != 1"},
+	"tn":    {NPlurals: 2, Plural: "n != 1"},
+	"tr":    {NPlurals: 2, Plural: "n != 1"},
+	"ts":    {NPlurals: 2, Plural: "n != 1"},
+	"ug":    {NPlurals: 2, Plural: "n != 1"},
+	"ur":    {NPlurals: 2, Plural: "n != 1"},
+	"uz":    {NPlurals: 2, Plural: "n != 1"},
+	"ve":    {NPlurals: 2, Plural: "n != 1"},
+	"vo":    {NPlurals: 2, Plural: "n != 1"},
+	"vun":   {NPlurals: 2, Plural: "n != 1"},
+	"wae":   {NPlurals: 2, Plural: "n != 1"},
+	"xh":    {NPlurals: 2, Plural: "n != 1"},
+	"xog":   {NPlurals: 2, Plural: "n != 1"},
+	"yi":    {NPlurals: 2, Plural: "n != 1"},
+
+	// One form for 0 and 1 and another for everything else.
+	"ak":  {NPlurals: 2, Plural: "n > 1"},
+	"am":  {NPlurals: 2, Plural: "n > 1"},
+	"as":  {NPlurals: 2, Plural: "n > 1"},
+	"bho": {NPlurals: 2, Plural: "n > 1"},
+	"bn":  {NPlurals: 2, Plural: "n > 1"},
+	"doi": {NPlurals: 2, Plural: "n > 1"},
+	"fa":  {NPlurals: 2, Plural: "n > 1"},
+	"ff":  {NPlurals: 2, Plural: "n > 1"},
+	"fr":  {NPlurals: 2, Plural: "n > 1"},
+	"gu":  {NPlurals: 2, Plural: "n > 1"},
+	"guw": {NPlurals: 2, Plural: "n > 1"},
+	"hi":  {NPlurals: 2, Plural: "n > 1"},
+	"hy":  {NPlurals: 2, Plural: "n > 1"},
+	"kab": {NPlurals: 2, Plural: "n > 1"},
+	"kn":  {NPlurals: 2, Plural: "n > 1"},
+	"ln":  {NPlurals: 2, Plural: "n > 1"},
+	"mg":  {NPlurals: 2, Plural: "n > 1"},
+	"nso": {NPlurals: 2, Plural: "n > 1"},
+	"pa":  {NPlurals: 2, Plural: "n > 1"},
+	"pcm": {NPlurals: 2, Plural: "n > 1"},
+	"pt":  {NPlurals: 2, Plural: "n > 1"},
+	"si":  {NPlurals: 2, Plural: "n > 1"},
+	"ti":  {NPlurals: 2, Plural: "n > 1"},
+	"wa":  {NPlurals: 2, Plural: "n > 1"},
+	"zu":  {NPlurals: 2, Plural: "n > 1"},
+
+	// Two forms with special cases.
+	"ceb": {NPlurals: 2, Plural: "n != 1 && n != 2 && n != 3 && (n%10 == 4 || n%10 == 6 || n%10 == 9)"},
+	"fil": {NPlurals: 2, Plural: "n != 1 && n != 2 && n != 3 && (n%10 == 4 || n%10 == 6 || n%10 == 9)"},
+	"is":  {NPlurals: 2, Plural: "n%10 != 1 || n%100 == 11"},
+	"mk":  {NPlurals: 2, Plural: "n%10 != 1 || n%100 == 11"},
+	"tl":  {NPlurals: 2, Plural: "n != 1 && n != 2 && n != 3 && (n%10 == 4 || n%10 == 6 || n%10 == 9)"},
+	"tzm": {NPlurals: 2, Plural: "n >= 2 && (n < 11 || n > 99)"},
+
+	// Three forms.
+	"be":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"bs":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"cs":  {NPlurals: 3, Plural: "n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 2"},
+	"he":  {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"hr":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"iu":  {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"ksh": {NPlurals: 3, Plural: "n == 0 ? 0 : n == 1 ? 1 : 2"},
+	"lag": {NPlurals: 3, Plural: "n == 0 ? 0 : n == 1 ? 1 : 2"},
+	"lt":  {NPlurals: 3, Plural: "n%10 == 1 && (n%100 < 11 || n%100 > 19) ? 0 : n%10 >= 2 && (n%100 < 11 || n%100 > 19) ? 1 : 2"},
+	"lv":  {NPlurals: 3, Plural: "n%10 == 0 || n%100 >= 11 && n%100 <= 19 ? 0 : n%10 == 1 && n%100 != 11 ? 1 : 2"},
+	"naq": {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"pl":  {NPlurals: 3, Plural: "n == 1 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"prg": {NPlurals: 3, Plural: "n%10 == 0 || n%100 >= 11 && n%100 <= 19 ? 0 : n%10 == 1 && n%100 != 11 ? 1 : 2"},
+	"ro":  {NPlurals: 3, Plural: "n == 1 ? 0 : n == 0 || n%100 >= 1 && n%100 <= 19 ? 1 : 2"},
+	"ru":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"se":  {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"sh":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"shi": {NPlurals: 3, Plural: "n <= 1 ? 0 : n <= 10 ? 1 : 2"},
+	"sk":  {NPlurals: 3, Plural: "n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 2"},
+	"sma": {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"smj": {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"smn": {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"sms": {NPlurals: 3, Plural: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+	"sr":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+	"uk":  {NPlurals: 3, Plural: "n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2"},
+
+	// Four or more forms.
+	"dsb": {NPlurals: 4, Plural: "n%100 == 1 ? 0 : n%100 == 2 ? 1 : n%100 == 3 || n%100 == 4 ? 2 : 3"},
+	"gd":  {NPlurals: 4, Plural: "n == 1 || n == 11 ? 0 : n == 2 || n == 12 ? 1 : n >= 3 && n <= 10 || n >= 13 && n <= 19 ? 2 : 3"},
+	"gv":  {NPlurals: 4, Plural: "n%10 == 1 ? 0 : n%10 == 2 ? 1 : n%20 == 0 ? 2 : 3"},
+	"hsb": {NPlurals: 4, Plural: "n%100 == 1 ? 0 : n%100 == 2 ? 1 : n%100 == 3 || n%100 == 4 ? 2 : 3"},
+	"sl":  {NPlurals: 4, Plural: "n%100 == 1 ? 0 : n%100 == 2 ? 1 : n%100 == 3 || n%100 == 4 ? 2 : 3"},
+	"br":  {NPlurals: 5, Plural: "n%10 == 1 && n%100 != 11 && n%100 != 71 && n%100 != 91 ? 0 : n%10 == 2 && n%100 != 12 && n%100 != 72 && n%100 != 92 ? 1 : (n%10 == 3 || n%10 == 4 || n%10 == 9) && (n%100 < 10 || n%100 > 19) && (n%100 < 70 || n%100 > 79) && (n%100 < 90 || n%100 > 99) ? 2 : n != 0 && n%1000000 == 0 ? 3 : 4"},
+	"ga":  {NPlurals: 5, Plural: "n == 1 ? 0 : n == 2 ? 1 : n >= 3 && n <= 6 ? 2 : n >= 7 && n <= 10 ? 3 : 4"},
+	"mt":  {NPlurals: 5, Plural: "n == 1 ? 0 : n == 2 ? 1 : n == 0 || n%100 >= 3 && n%100 <= 10 ? 2 : n%100 >= 11 && n%100 <= 19 ? 3 : 4"},
+	"ar":  {NPlurals: 6, Plural: "n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n%100 >= 3 && n%100 <= 10 ? 3 : n%100 >= 11 ? 4 : 5"},
+	"ars": {NPlurals: 6, Plural: "n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n%100 >= 3 && n%100 <= 10 ? 3 : n%100 >= 11 ? 4 : 5"},
+	"cy":  {NPlurals: 6, Plural: "n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n == 3 ? 3 : n == 6 ? 4 : 5"},
+}
+
+// decimalPluralRules contains the CLDR cardinal plural rules for decimals
+// of the languages of pluralRules in which n alone doesn't select the form of
+// a decimal, such as "1.0" in English or "1.5" in French. The rules use the
+// CLDR operands, so they must be compiled with pluralsparser.CompileCLDR and
+// are never written to a Plural-Forms header. For integers they select the
+// same form as the rule of pluralRules with the same key.
+//
+// Belarusian, Polish, Russian and Ukrainian have no gettext form for the
+// CLDR "other" category of decimals, so decimals select the form of "few".
+var decimalPluralRules = map[string]string{
+	// Only 1 is singular, and 1.0 is plural.
+	"ast":   "i != 1 || v != 0",
+	"ca":    "i != 1 || v != 0",
+	"de":    "i != 1 || v != 0",
+	"en":    "i != 1 || v != 0",
+	"et":    "i != 1 || v != 0",
+	"fi":    "i != 1 || v != 0",
+	"fy":    "i != 1 || v != 0",
+	"gl":    "i != 1 || v != 0",
+	"ia":    "i != 1 || v != 0",
+	"io":    "i != 1 || v != 0",
+	"lij":   "i != 1 || v != 0",
+	"nl":    "i != 1 || v != 0",
+	"pt_PT": "i != 1 || v != 0",
+	"sc":    "i != 1 || v != 0",
+	"sv":    "i != 1 || v != 0",
+	"sw":    "i != 1 || v != 0",
+	"ur":    "i != 1 || v != 0",
+	"yi":    "i != 1 || v != 0",
+	"da":    "n != 1 && (t == 0 || i > 1)",
+
+	// The integers 0 and 1 are singular.
+	"ak":  "t != 0 || i > 1",
+	"bho": "t != 0 || i > 1",
+	"guw": "t != 0 || i > 1",
+	"ln":  "t != 0 || i > 1",
+	"mg":  "t != 0 || i > 1",
+	"nso": "t != 0 || i > 1",
+	"pa":  "t != 0 || i > 1",
+	"ti":  "t != 0 || i > 1",
+	"wa":  "t != 0 || i > 1",
+
+	// Every number below 1 and 1 itself are singular.
+	"am":  "i != 0 && n != 1",
+	"as":  "i != 0 && n != 1",
+	"bn":  "i != 0 && n != 1",
+	"doi": "i != 0 && n != 1",
+	"fa":  "i != 0 && n != 1",
+	"gu":  "i != 0 && n != 1",
+	"hi":  "i != 0 && n != 1",
+	"kn":  "i != 0 && n != 1",
+	"pcm": "i != 0 && n != 1",
+	"zu":  "i != 0 && n != 1",
+
+	// Every number below 2 is singular.
+	"ff":  "i > 1",
+	"fr":  "i > 1",
+	"hy":  "i > 1",
+	"kab": "i > 1",
+	"pt":  "i > 1",
+	"si":  "n != 0 && n != 1 && (i != 0 || f != 1)",
+
+	// Two forms with special cases.
+	"ceb": "v == 0 ? i != 1 && i != 2 && i != 3 && (i%10 == 4 || i%10 == 6 || i%10 == 9) : f%10 == 4 || f%10 == 6 || f%10 == 9",
+	"fil": "v == 0 ? i != 1 && i != 2 && i != 3 && (i%10 == 4 || i%10 == 6 || i%10 == 9) : f%10 == 4 || f%10 == 6 || f%10 == 9",
+	"tl":  "v == 0 ? i != 1 && i != 2 && i != 3 && (i%10 == 4 || i%10 == 6 || i%10 == 9) : f%10 == 4 || f%10 == 6 || f%10 == 9",
+	"is":  "(t != 0 || i%10 != 1 || i%100 == 11) && (t%10 != 1 || t%100 == 11)",
+	"mk":  "(v != 0 || i%10 != 1 || i%100 == 11) && (f%10 != 1 || f%100 == 11)",
+	"tzm": "t != 0 || i >= 2 && (i < 11 || i > 99)",
+
+	// Three forms, in which decimals select the form of "few".
+	"be": "t != 0 ? 1 : i%10 == 1 && i%100 != 11 ? 0 : i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) ? 1 : 2",
+	"pl": "v != 0 ? 1 : i == 1 ? 0 : i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) ? 1 : 2",
+	"ru": "v != 0 ? 1 : i%10 == 1 && i%100 != 11 ? 0 : i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) ? 1 : 2",
+	"uk": "v != 0 ? 1 : i%10 == 1 && i%100 != 11 ? 0 : i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) ? 1 : 2",
+
+	// Three forms, in which the fraction digits select the form as well.
+	"bs": "v == 0 && i%10 == 1 && i%100 != 11 || f%10 == 1 && f%100 != 11 ? 0 : v == 0 && i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) || f%10 >= 2 && f%10 <= 4 && (f%100 < 12 || f%100 > 14) ? 1 : 2",
+	"hr": "v == 0 && i%10 == 1 && i%100 != 11 || f%10 == 1 && f%100 != 11 ? 0 : v == 0 && i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) || f%10 >= 2 && f%10 <= 4 && (f%100 < 12 || f%100 > 14) ? 1 : 2",
+	"sh": "v == 0 && i%10 == 1 && i%100 != 11 || f%10 == 1 && f%100 != 11 ? 0 : v == 0 && i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) || f%10 >= 2 && f%10 <= 4 && (f%100 < 12 || f%100 > 14) ? 1 : 2",
+	"sr": "v == 0 && i%10 == 1 && i%100 != 11 || f%10 == 1 && f%100 != 11 ? 0 : v == 0 && i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14) || f%10 >= 2 && f%10 <= 4 && (f%100 < 12 || f%100 > 14) ? 1 : 2",
+}
+
+// PluralRuleForLanguage returns the plural rule of the built-in table for the
+// language of locale, which may be a gettext locale such as "pt_BR.UTF-8" or
+// a BCP 47 tag such as "pt-BR". A rule for the territory of the locale takes
+// precedence over the rule for its language.
+//
+// The rules are those of the Unicode CLDR for integers, which gettext uses
+// to count. false is returned if the language is not in the table.
+func PluralRuleForLanguage(locale string) (PluralRule, bool) {
+	key, ok := pluralRuleKey(locale)
+	if !ok {
+		return PluralRule{}, false
+	}
+
+	rule := pluralRules[key]
+	rule.Source = PluralRuleLanguage
+	return rule, true
+}
+
+// decimalPluralRuleForLanguage returns the rule of decimalPluralRules for the
+// language of locale, which is found as by PluralRuleForLanguage. false is
+// returned if the rule of PluralRuleForLanguage is also used for decimals.
+func decimalPluralRuleForLanguage(locale string) (string, bool) {
+	key, ok := pluralRuleKey(locale)
+	if !ok {
+		return "", false
+	}
+
+	plural, ok := decimalPluralRules[key]
+	return plural, ok
+}
+
+// pluralRuleKey returns the key of pluralRules for the language of locale.
+func pluralRuleKey(locale string) (string, bool) {
+	if idx := strings.IndexAny(locale, ".@"); idx >= 0 {
+		locale = locale[:idx]
+	}
+
+	subtags := strings.Split(strings.ReplaceAll(locale, "-", "_"), "_")
+	if subtags[0] == "" {
+		return "", false
+	}
+
+	language := strings.ToLower(subtags[0])
+	keys := []string{language}
+	if len(subtags) > 1 {
+		keys = append([]string{language + "_" + strings.ToUpper(subtags[len(subtags)-1])}, keys...)
+	}
+
+	for _, key := range keys {
+		if _, ok := pluralRules[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
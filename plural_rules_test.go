@@ -0,0 +1,251 @@
+package gogettext
+
+import (
+	"github.com/taylor-s-dean/gogettext/plurals-parser"
+)
+
+func (t *TestSuite) TestPluralRules_Valid() {
+	for language, rule := range pluralRules {
+		expr, err := pluralsparser.Compile(rule.Plural)
+		t.Require().NoError(err, language)
+		t.NoError(checkPluralRange(expr, rule.NPlurals), language)
+
+		// Every form must be reachable.
+		forms := map[uint64]bool{}
+		for n := uint64(0); n <= pluralRangeLimit; n++ {
+			idx, _ := expr.Eval(n)
+			forms[idx] = true
+		}
+		for _, n := range pluralRangeQuantities {
+			idx, _ := expr.Eval(n)
+			forms[idx] = true
+		}
+		t.Len(forms, rule.NPlurals, language)
+		t.Equal(PluralRuleDefault, rule.Source, language)
+	}
+}
+
+func (t *TestSuite) TestDecimalPluralRules_Valid() {
+	for language, plural := range decimalPluralRules {
+		rule, ok := pluralRules[language]
+		t.Require().True(ok, language)
+
+		// Plural-Forms headers can't use the CLDR operands.
+		_, err := pluralsparser.Compile(plural)
+		t.Error(err, language)
+
+		expr, err := pluralsparser.CompileCLDR(plural)
+		t.Require().NoError(err, language)
+		integerExpr := pluralsparser.MustCompile(rule.Plural)
+
+		check := func(n uint64) {
+			idx, err := expr.Eval(n)
+			t.NoError(err, language)
+			expected, _ := integerExpr.Eval(n)
+			t.Equal(expected, idx, "%s for n = %d", language, n)
+		}
+		for n := uint64(0); n <= pluralRangeLimit; n++ {
+			check(n)
+		}
+		for _, n := range pluralRangeQuantities {
+			check(n)
+		}
+	}
+}
+
+func (t *TestSuite) TestPluralRuleForLanguage() {
+	for locale, expected := range map[string]string{
+		"ru":          pluralRules["ru"].Plural,
+		"RU":          pluralRules["ru"].Plural,
+		"uk_UA.UTF-8": pluralRules["uk"].Plural,
+		"sr@latin":    pluralRules["sr"].Plural,
+		"pt":          "n > 1",
+		"pt_BR":       "n > 1",
+		"pt-pt":       "n != 1",
+		"zh-Hant-TW":  "0",
+		"ar_EG":       pluralRules["ar"].Plural,
+	} {
+		rule, ok := PluralRuleForLanguage(locale)
+		t.True(ok, locale)
+		t.Equal(expected, rule.Plural, locale)
+		t.Equal(PluralRuleLanguage, rule.Source, locale)
+	}
+
+	for _, locale := range []string{"", "tlh", "_RU", ".UTF-8"} {
+		_, ok := PluralRuleForLanguage(locale)
+		t.False(ok, locale)
+	}
+}
+
+func (t *TestSuite) TestPluralRules_Forms() {
+	for language, expected := range map[string][]uint64{
+		"en": {1, 0, 1, 1, 1, 1, 1, 1},
+		"fr": {0, 0, 1, 1, 1, 1, 1, 1},
+		"ru": {2, 0, 1, 2, 2, 0, 1, 2},
+		"pl": {2, 0, 1, 2, 2, 2, 1, 2},
+		"ar": {0, 1, 2, 3, 4, 4, 5, 4},
+		"cy": {0, 1, 2, 5, 5, 5, 5, 5},
+	} {
+		expr, err := pluralsparser.Compile(pluralRules[language].Plural)
+		t.Require().NoError(err)
+
+		actual := []uint64{}
+		for _, n := range []uint64{0, 1, 2, 5, 11, 21, 102, 111} {
+			idx, err := expr.Eval(n)
+			t.NoError(err)
+			actual = append(actual, idx)
+		}
+		t.Equal(expected, actual, language)
+	}
+}
+
+func (t *TestSuite) TestPluralRules_Decimals() {
+	for language, expected := range map[string]map[string]uint64{
+		"en":  {"1": 0, "1.0": 1, "1.5": 1, "0.5": 1},
+		"es":  {"1": 0, "1.0": 0, "1.5": 1, "0.5": 1},
+		"da":  {"1": 0, "1.0": 0, "0.5": 0, "1.5": 0, "2.5": 1, "0.0": 1},
+		"fr":  {"0.5": 0, "1.5": 0, "1.99": 0, "2.0": 1},
+		"hi":  {"0.5": 0, "1.0": 0, "1.5": 1},
+		"ak":  {"0.0": 0, "1.0": 0, "0.5": 1, "1.5": 1},
+		"si":  {"0.1": 0, "0.2": 1, "1.0": 0, "1.1": 1},
+		"is":  {"21": 0, "11": 1, "0.1": 0, "0.11": 1, "1.0": 0},
+		"mk":  {"21": 0, "11": 1, "0.1": 0, "0.11": 1, "1.0": 1},
+		"fil": {"1": 0, "4": 1, "0.1": 0, "0.4": 1},
+		"tzm": {"1": 0, "11": 0, "1.5": 1, "11.0": 0},
+		"ru":  {"1": 0, "1.0": 1, "1.5": 1, "21": 0, "5": 2, "5.0": 1},
+		"be":  {"1": 0, "1.0": 0, "1.5": 1, "5.0": 2},
+		"pl":  {"1": 0, "1.5": 1, "22": 1, "25": 2},
+		"hr":  {"1": 0, "0.1": 0, "0.2": 1, "0.5": 2, "1.5": 2, "2.0": 2},
+	} {
+		expr, err := pluralsparser.Compile(pluralRules[language].Plural)
+		if plural, ok := decimalPluralRules[language]; ok {
+			expr, err = pluralsparser.CompileCLDR(plural)
+		}
+		t.Require().NoError(err, language)
+
+		for decimal, idx := range expected {
+			ops, err := pluralsparser.ParseOperands(decimal)
+			t.Require().NoError(err, decimal)
+
+			actual, err := expr.EvalOperands(ops)
+			t.NoError(err)
+			t.Equal(idx, actual, "%s for %s", decimal, language)
+		}
+	}
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromString_LanguagePluralRule() {
+	const fileContents = `
+msgid ""
+msgstr "Language: ru\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+`
+
+	mc, err := NewMessageCatalogFromString(fileContents)
+	t.Require().NoError(err)
+	t.Equal("%d файла", mc.NGettext("%d file", "%d files", 3))
+	t.Equal("%d файлов", mc.NGettext("%d file", "%d files", 5))
+
+	rule, err := mc.GetPluralRule()
+	t.NoError(err)
+	t.Equal(PluralRuleLanguage, rule.Source)
+	t.Equal(3, rule.NPlurals)
+
+	mc, err = NewMessageCatalogFromString(fileContents, Language("en_US"))
+	t.Require().NoError(err)
+	t.Equal("%d файла", mc.NGettext("%d file", "%d files", 5))
+}
+
+func (t *TestSuite) TestNewMessageCatalogFromString_TemplateHeader() {
+	const fileContents = `# SOME DESCRIPTIVE TITLE.
+# Copyright (C) YEAR THE PACKAGE'S COPYRIGHT HOLDER
+# This file is distributed under the same license as the PACKAGE package.
+# FIRST AUTHOR <EMAIL@ADDRESS>, YEAR.
+#
+#, fuzzy
+msgid ""
+msgstr ""
+"Project-Id-Version: PACKAGE VERSION\n"
+"Report-Msgid-Bugs-To: \n"
+"POT-Creation-Date: 2021-03-05 11:22+0000\n"
+"PO-Revision-Date: YEAR-MO-DA HO:MI+ZONE\n"
+"Last-Translator: FULL NAME <EMAIL@ADDRESS>\n"
+"Language-Team: LANGUAGE <LL@li.org>\n"
+"Language: \n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=CHARSET\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Plural-Forms: nplurals=INTEGER; plural=EXPRESSION;\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+`
+
+	mc, err := NewMessageCatalogFromString(fileContents)
+	t.Require().NoError(err)
+	rule, err := mc.GetPluralRule()
+	t.NoError(err)
+	t.Equal(PluralRule{NPlurals: defaultNPlurals, Plural: defaultPluralForms, Source: PluralRuleDefault}, rule)
+
+	mc, err = NewMessageCatalogFromString(fileContents, Language("ru"))
+	t.Require().NoError(err)
+	rule, err = mc.GetPluralRule()
+	t.NoError(err)
+	t.Equal(pluralRules["ru"].Plural, rule.Plural)
+	t.Equal(PluralRuleLanguage, rule.Source)
+
+	warnings, err := mc.LintPluralForms()
+	t.NoError(err)
+	t.Empty(warnings)
+}
+
+func (t *TestSuite) TestMessageCatalog_LintPluralForms() {
+	warnings, err := t.mc.LintPluralForms()
+	t.NoError(err)
+	t.Empty(warnings)
+
+	for value, expected := range map[string][]string{
+		"nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);": nil,
+		"nplurals=2; plural=n != 1;": {
+			"nplurals=2 differs from nplurals=3 for ru",
+			"plural=n != 1 selects form 1 for n = 0, but plural=" + pluralRules["ru"].Plural + " for ru selects form 2",
+		},
+		"nplurals=3; plural=n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 ? 1 : 2;": {
+			"plural=n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 ? 1 : 2 selects form 1 for n = 12, but plural=" + pluralRules["ru"].Plural + " for ru selects form 2",
+		},
+	} {
+		mc, err := NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Language: ru\\nPlural-Forms: " + value + "\\n\"\n")
+		t.Require().NoError(err, value)
+
+		warnings, err := mc.LintPluralForms()
+		t.NoError(err)
+		if expected == nil {
+			t.Empty(warnings, value)
+		} else {
+			t.Equal(expected, warnings, value)
+		}
+	}
+
+	mc, err := NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Plural-Forms: nplurals=2; plural=n != 1;\\n\"\n", Language("fr"))
+	t.Require().NoError(err)
+	warnings, err = mc.LintPluralForms()
+	t.NoError(err)
+	t.Equal([]string{"plural=n != 1 selects form 1 for n = 0, but plural=n > 1 for fr selects form 0"}, warnings)
+
+	mc, err = NewMessageCatalogFromString("msgid \"\"\nmsgstr \"Language: tlh\\nPlural-Forms: nplurals=1; plural=0;\\n\"\n")
+	t.Require().NoError(err)
+	warnings, err = mc.LintPluralForms()
+	t.NoError(err)
+	t.Empty(warnings)
+
+	mc.store = nil
+	_, err = mc.LintPluralForms()
+	t.EqualError(err, ErrorNilMessageCatalog.Error())
+}
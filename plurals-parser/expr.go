@@ -0,0 +1,354 @@
+package pluralsparser
+
+import (
+	"fmt"
+)
+
+// Expr is a compiled Plural-Forms expression. An Expr is immutable and may
+// be evaluated by any number of goroutines at once.
+type Expr struct {
+	expression string
+	root       node
+}
+
+// Eval evaluates the expression while substituting the variable "n" with the
+// provided value. It is the same as EvalOperands with Operands{I: n}.
+//
+// Returns the resulting index into the plural array and an error if an
+// error was encountered, such as a *DivisionByZeroError or an
+// *UnderflowError.
+func (e *Expr) Eval(n uint64) (uint64, error) {
+	return e.EvalOperands(Operands{I: n})
+}
+
+// EvalOperands evaluates the expression for the number described by ops.
+// The variable "n" is the exact value of the number, I + F / 10^V, and in
+// expressions compiled with CompileCLDR the variables "i", "v", "w", "f", "t"
+// and "e" are the operands of the same name.
+//
+// The fraction of n is kept by comparisons, "+", "-" and the left operand
+// of "%", so "n != 1" is true and "n % 10 == 1" is false for 1.5. It is
+// discarded by "*", "/" and the right operand of "%", and the resulting
+// index is the integer part of the value of the expression.
+//
+// Returns the resulting index into the plural array and an error if an
+// error was encountered, such as a *DivisionByZeroError or an
+// *UnderflowError.
+func (e *Expr) EvalOperands(ops Operands) (uint64, error) {
+	if ops.V >= uint64(len(pow10)) {
+		return 0, fmt.Errorf("invalid operands: v = %d exceeds %d", ops.V, len(pow10)-1)
+	}
+	if ops.F >= pow10[ops.V] {
+		return 0, fmt.Errorf("invalid operands: f = %d has more than v = %d digits", ops.F, ops.V)
+	}
+
+	res, err := e.root.eval(env{ops: ops, scale: pow10[ops.V]})
+	if err != nil {
+		err.setExpression(e.expression)
+		return 0, err
+	}
+	return res.i, nil
+}
+
+// String returns the source text of the expression.
+func (e *Expr) String() string {
+	return e.expression
+}
+
+// DivisionByZeroError is returned when the divisor of a "/" or "%" operator
+// is zero. Compile returns it for divisors that are zero for every n and
+// Eval returns it for divisors that are zero for the provided n.
+type DivisionByZeroError struct {
+	// Expression is the source text of the whole expression.
+	Expression string
+	// Op is the operator whose divisor is zero, either "/" or "%".
+	Op string
+	// Constant is true if the divisor is zero for every n.
+	Constant bool
+	// N is the value of n for which the divisor is zero, or its integer
+	// part if n is a decimal. It is only set if Constant is false.
+	N uint64
+}
+
+func (e *DivisionByZeroError) Error() string {
+	kind := "division"
+	if e.Op == "%" {
+		kind = "modulo"
+	}
+
+	if e.Constant {
+		return fmt.Sprintf("%s by zero in %q", kind, e.Expression)
+	}
+	return fmt.Sprintf("%s by zero in %q for n = %d", kind, e.Expression, e.N)
+}
+
+func (e *DivisionByZeroError) setExpression(expression string) { e.Expression = expression }
+func (e *DivisionByZeroError) setConstant()                    { e.Constant = true }
+
+// UnderflowError is returned when the right operand of a "-" operator is
+// greater than its left operand. All arithmetic is done on unsigned
+// integers, so the difference can't be represented. Compile returns it for
+// differences that underflow for every n and Eval returns it for differences
+// that underflow for the provided n.
+type UnderflowError struct {
+	// Expression is the source text of the whole expression.
+	Expression string
+	// X and Y are the integer parts of the operands of the subtraction x - y.
+	X, Y uint64
+	// Constant is true if the subtraction underflows for every n.
+	Constant bool
+	// N is the value of n for which the subtraction underflows, or its
+	// integer part if n is a decimal. It is only set if Constant is false.
+	N uint64
+}
+
+func (e *UnderflowError) Error() string {
+	if e.Constant {
+		return fmt.Sprintf("%d - %d underflows in %q", e.X, e.Y, e.Expression)
+	}
+	return fmt.Sprintf("%d - %d underflows in %q for n = %d", e.X, e.Y, e.Expression, e.N)
+}
+
+func (e *UnderflowError) setExpression(expression string) { e.Expression = expression }
+func (e *UnderflowError) setConstant()                    { e.Constant = true }
+
+// evalError is an error that can occur while evaluating an expression. The
+// nodes don't know the source text of the expression or whether they are
+// being folded, so both are filled in by the caller.
+type evalError interface {
+	error
+	setExpression(expression string)
+	setConstant()
+}
+
+// pow10 contains the powers of ten that a fraction of up to 18 digits is
+// scaled by. The sum of two such fractions still fits in a uint64.
+var pow10 = [...]uint64{
+	1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18,
+}
+
+// env is the environment an expression is evaluated in.
+type env struct {
+	ops Operands
+	// scale is 10^ops.V, the denominator of the fraction of every value.
+	scale uint64
+}
+
+// value is a non-negative decimal number i + f / scale. Only n has a
+// fraction, which is carried through the operators that keep it.
+type value struct {
+	i, f uint64
+}
+
+func (x value) less(y value) bool {
+	return x.i < y.i || x.i == y.i && x.f < y.f
+}
+
+func (x value) isTrue() bool {
+	return x.i != 0 || x.f != 0
+}
+
+// node is a node of the syntax tree of an expression.
+type node interface {
+	eval(e env) (value, evalError)
+}
+
+// numberNode is a numeric literal.
+type numberNode uint64
+
+func (x numberNode) eval(env) (value, evalError) {
+	return value{i: uint64(x)}, nil
+}
+
+// variableNode is a reference to the variable of the specified name, which
+// is "n" or one of the operands.
+type variableNode string
+
+func (x variableNode) eval(e env) (value, evalError) {
+	switch x {
+	case "n":
+		return value{i: e.ops.I, f: e.ops.F}, nil
+	case "i":
+		return value{i: e.ops.I}, nil
+	case "v":
+		return value{i: e.ops.V}, nil
+	case "w":
+		return value{i: e.ops.W}, nil
+	case "f":
+		return value{i: e.ops.F}, nil
+	case "t":
+		return value{i: e.ops.T}, nil
+	case "e":
+		return value{i: e.ops.E}, nil
+	}
+	panic("pluralsparser: unknown variable")
+}
+
+// binaryNode applies the operator op, which is one of the operator tokens,
+// to the operands x and y.
+type binaryNode struct {
+	op   int
+	x, y node
+}
+
+func (b *binaryNode) eval(e env) (value, evalError) {
+	x, err := b.x.eval(e)
+	if err != nil {
+		return value{}, err
+	}
+
+	// The logical operators short-circuit as they do in C.
+	switch {
+	case b.op == tokAND && !x.isTrue():
+		return value{i: 0}, nil
+	case b.op == tokOR && x.isTrue():
+		return value{i: 1}, nil
+	}
+
+	y, err := b.y.eval(e)
+	if err != nil {
+		return value{}, err
+	}
+
+	switch b.op {
+	case tokMOD:
+		if y.i == 0 {
+			return value{}, &DivisionByZeroError{Op: "%", N: e.ops.I}
+		}
+		return value{i: x.i % y.i, f: x.f}, nil
+	case tokMULTIPLY:
+		return value{i: x.i * y.i}, nil
+	case tokDIVIDE:
+		if y.i == 0 {
+			return value{}, &DivisionByZeroError{Op: "/", N: e.ops.I}
+		}
+		return value{i: x.i / y.i}, nil
+	case tokADD:
+		res := value{i: x.i + y.i, f: x.f + y.f}
+		if res.f >= e.scale {
+			res.i++
+			res.f -= e.scale
+		}
+		return res, nil
+	case tokSUBTRACT:
+		if x.less(y) {
+			return value{}, &UnderflowError{X: x.i, Y: y.i, N: e.ops.I}
+		}
+		res := value{i: x.i - y.i, f: x.f - y.f}
+		if x.f < y.f {
+			res.i--
+			res.f += e.scale
+		}
+		return res, nil
+	case tokLT:
+		return boolToValue(x.less(y)), nil
+	case tokLE:
+		return boolToValue(!y.less(x)), nil
+	case tokGT:
+		return boolToValue(y.less(x)), nil
+	case tokGE:
+		return boolToValue(!x.less(y)), nil
+	case tokEQ:
+		return boolToValue(x == y), nil
+	case tokNE:
+		return boolToValue(x != y), nil
+	case tokAND, tokOR:
+		return boolToValue(y.isTrue()), nil
+	}
+	panic("pluralsparser: unknown operator")
+}
+
+// ternaryNode evaluates to then if cond is non-zero and to els otherwise.
+// Only the chosen branch is evaluated.
+type ternaryNode struct {
+	cond, then, els node
+}
+
+func (t *ternaryNode) eval(e env) (value, evalError) {
+	cond, err := t.cond.eval(e)
+	if err != nil {
+		return value{}, err
+	}
+	if cond.isTrue() {
+		return t.then.eval(e)
+	}
+	return t.els.eval(e)
+}
+
+// fold replaces the subexpressions of x that don't depend on a variable with
+// their value. Operands that are never evaluated, such as the branch of a ternary
+// whose condition is constant, are dropped without being folded.
+//
+// A *DivisionByZeroError is returned if a divisor folds to zero and an
+// *UnderflowError if a constant subtraction underflows.
+func fold(x node) (node, evalError) {
+	switch x := x.(type) {
+	case *binaryNode:
+		left, err := fold(x.x)
+		if err != nil {
+			return nil, err
+		}
+
+		if value, ok := left.(numberNode); ok {
+			switch {
+			case x.op == tokAND && value == 0:
+				return numberNode(0), nil
+			case x.op == tokOR && value != 0:
+				return numberNode(1), nil
+			}
+		}
+
+		right, err := fold(x.y)
+		if err != nil {
+			return nil, err
+		}
+
+		folded := &binaryNode{op: x.op, x: left, y: right}
+		_, leftConstant := left.(numberNode)
+		divisor, rightConstant := right.(numberNode)
+		isDivision := x.op == tokDIVIDE || x.op == tokMOD
+		if !(leftConstant && rightConstant) && !(isDivision && rightConstant && divisor == 0) {
+			return folded, nil
+		}
+
+		res, err := folded.eval(env{scale: 1})
+		if err != nil {
+			err.setConstant()
+			return nil, err
+		}
+		return numberNode(res.i), nil
+
+	case *ternaryNode:
+		cond, err := fold(x.cond)
+		if err != nil {
+			return nil, err
+		}
+
+		if value, ok := cond.(numberNode); ok {
+			if value != 0 {
+				return fold(x.then)
+			}
+			return fold(x.els)
+		}
+
+		then, err := fold(x.then)
+		if err != nil {
+			return nil, err
+		}
+		els, err := fold(x.els)
+		if err != nil {
+			return nil, err
+		}
+		return &ternaryNode{cond: cond, then: then, els: els}, nil
+	}
+
+	return x, nil
+}
+
+func boolToValue(b bool) value {
+	if b {
+		return value{i: 1}
+	}
+	return value{i: 0}
+}
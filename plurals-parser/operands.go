@@ -0,0 +1,110 @@
+package pluralsparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operands are the CLDR plural operands of a decimal number, which are
+// available to an expression compiled with CompileCLDR as the variables of
+// the same name. The zero value of the fields other than I describes the
+// integer I.
+//
+// See https://unicode.org/reports/tr35/tr35-numbers.html#Operands.
+type Operands struct {
+	// I is the integer digits of the absolute value of the number.
+	I uint64
+	// V is the number of visible fraction digits, with trailing zeros.
+	V uint64
+	// W is the number of visible fraction digits, without trailing zeros.
+	W uint64
+	// F is the visible fraction digits, with trailing zeros.
+	F uint64
+	// T is the visible fraction digits, without trailing zeros.
+	T uint64
+	// E is the exponent of a number in compact decimal notation.
+	E uint64
+}
+
+// ParseOperands returns the Operands of a decimal number as it is
+// displayed, such as "1", "-1.50" or "1.2e3". The exponent of a number in
+// compact decimal notation such as "1.2 thousand" is written with "e" or
+// "c", so that "1.2e3" is 1200 with E = 3.
+//
+// The fraction may have at most 18 digits.
+func ParseOperands(decimal string) (Operands, error) {
+	invalid := func() (Operands, error) {
+		return Operands{}, fmt.Errorf("invalid decimal %q", decimal)
+	}
+
+	s := strings.TrimPrefix(decimal, "-")
+	exponent := ""
+	if idx := strings.IndexAny(s, "ec"); idx >= 0 {
+		s, exponent = s[:idx], s[idx+1:]
+		if !isDigits(exponent) {
+			return invalid()
+		}
+	}
+
+	integer, fraction := s, ""
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		integer, fraction = s[:idx], s[idx+1:]
+		if !isDigits(fraction) {
+			return invalid()
+		}
+	}
+	if !isDigits(integer) {
+		return invalid()
+	}
+
+	ops := Operands{}
+	if len(exponent) > 0 {
+		e, err := strconv.ParseUint(exponent, 10, 64)
+		if err != nil || e > 20 {
+			return Operands{}, fmt.Errorf("decimal %q is out of range", decimal)
+		}
+		ops.E = e
+
+		// Shift the decimal point by the exponent.
+		for ; e > 0; e-- {
+			if len(fraction) > 0 {
+				integer, fraction = integer+fraction[:1], fraction[1:]
+			} else {
+				integer += "0"
+			}
+		}
+	}
+
+	var err error
+	if ops.I, err = strconv.ParseUint(integer, 10, 64); err != nil {
+		return Operands{}, fmt.Errorf("decimal %q is out of range", decimal)
+	}
+
+	if len(fraction) >= len(pow10) {
+		return Operands{}, fmt.Errorf("decimal %q has more than %d fraction digits", decimal, len(pow10)-1)
+	}
+	trimmed := strings.TrimRight(fraction, "0")
+	ops.V = uint64(len(fraction))
+	ops.W = uint64(len(trimmed))
+	if len(fraction) > 0 {
+		// The fraction has at most 18 digits, so it can't overflow.
+		ops.F, _ = strconv.ParseUint(fraction, 10, 64)
+	}
+	if len(trimmed) > 0 {
+		ops.T, _ = strconv.ParseUint(trimmed, 10, 64)
+	}
+	return ops, nil
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if !isNumber[c] {
+			return false
+		}
+	}
+	return true
+}
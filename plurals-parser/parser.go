@@ -18,9 +18,10 @@ import (
 
 //line ./plurals-parser/parser.yy:15
 type yySymType struct {
-	yys int
-	num uint64
-	str string
+	yys  int
+	num  uint64
+	str  string
+	node node
 }
 
 const tokIDENTIFIER = 57346
@@ -76,18 +77,25 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line ./plurals-parser/parser.yy:179
+//line ./plurals-parser/parser.yy:120
 
 const eof = 0
 
+// The generated parser reads yyErrorVerbose on every syntax error, so it is
+// only ever set here, before any expression can be compiled, to keep Compile
+// and Evaluate safe for concurrent use.
+func init() {
+	yyErrorVerbose = true
+}
+
 type yyLex struct {
-	line      []byte
-	peek      rune
-	idx       int
-	orig      []byte
-	Result    uint64
-	Variables map[string]uint64
-	Err       error
+	line     []byte
+	peek     rune
+	idx      int
+	orig     []byte
+	operands bool
+	Result   node
+	Err      error
 }
 
 var isNumber = map[rune]bool{
@@ -103,6 +111,17 @@ var isNumber = map[rune]bool{
 	'9': true,
 }
 
+// isOperand contains the CLDR plural operands, which are only variables of
+// expressions compiled with CompileCLDR.
+var isOperand = map[rune]bool{
+	'i': true,
+	'v': true,
+	'w': true,
+	'f': true,
+	't': true,
+	'e': true,
+}
+
 var isWhitespace = map[rune]bool{
 	' ':  true,
 	'\t': true,
@@ -162,7 +181,7 @@ func (x *yyLex) Lex(yylval *yySymType) (res int) {
 			return tokDIVIDE
 		case c == '%':
 			return tokMOD
-		case c == 'n':
+		case c == 'n' || x.operands && isOperand[c]:
 			yylval.str = string(c)
 			return tokIDENTIFIER
 		case isWhitespace[c]:
@@ -233,33 +252,78 @@ func (x *yyLex) Error(s string) {
 	x.Err = fmt.Errorf("parse error: %s\n%s\n%s\n", s, x.orig, ss.String())
 }
 
-func newLexer(line []byte, n uint64) *yyLex {
+func newLexer(line []byte, operands bool) *yyLex {
 	c, size := utf8.DecodeRune(line)
 	return &yyLex{
-		line:      line[size:],
-		peek:      c,
-		idx:       -1,
-		orig:      line,
-		Result:    0,
-		Variables: map[string]uint64{"n": n},
-		Err:       nil,
+		line:     line[size:],
+		peek:     c,
+		idx:      -1,
+		orig:     line,
+		operands: operands,
+		Result:   nil,
+		Err:      nil,
+	}
+}
+
+// Compile parses the provided Plural-Forms ternary string into an Expr that
+// can be evaluated for any value of the variable "n" without being parsed
+// again. Subexpressions that don't depend on "n" are evaluated once here.
+// As in GNU gettext, "n" is the only variable.
+//
+// Returns an error if the expression is not valid, which is a
+// *DivisionByZeroError if it divides by a constant zero and an
+// *UnderflowError if a constant subtraction underflows.
+func Compile(expression string) (*Expr, error) {
+	return compile(expression, false)
+}
+
+// CompileCLDR is like Compile but the expression may also use the CLDR
+// plural operands "i", "v", "w", "f", "t" and "e" described by Operands.
+// Such expressions are not valid Plural-Forms headers.
+func CompileCLDR(expression string) (*Expr, error) {
+	return compile(expression, true)
+}
+
+func compile(expression string, operands bool) (*Expr, error) {
+	l := newLexer([]byte(expression), operands)
+	yyParse(l)
+	if l.Err != nil {
+		return nil, l.Err
+	}
+
+	root, err := fold(l.Result)
+	if err != nil {
+		err.setExpression(expression)
+		return nil, err
 	}
+	return &Expr{expression: expression, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is not valid.
+func MustCompile(expression string) *Expr {
+	expr, err := Compile(expression)
+	if err != nil {
+		panic(err)
+	}
+	return expr
 }
 
 // Evaluate compiles and evalutes the provided Plural-Format ternary string
-// while supstituting the variable "n" with the provided value.
+// while supstituting the variable "n" with the provided value. Use Compile
+// to evaluate the same expression repeatedly.
 //
 // Returns the resulting index into the plural array and an error if an
 // error was encountered.
 func Evaluate(expression string, n uint64) (uint64, error) {
-	yyErrorVerbose = true
-	l := newLexer([]byte(expression), n)
-	yyParse(l)
-	return l.Result, l.Err
+	expr, err := Compile(expression)
+	if err != nil {
+		return 0, err
+	}
+	return expr.Eval(n)
 }
 
 //line yacctab:1
-var yyExca = [...]int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
@@ -269,7 +333,7 @@ const yyPrivate = 57344
 
 const yyLast = 84
 
-var yyAct = [...]int{
+var yyAct = [...]int8{
 	2, 3, 14, 15, 16, 25, 26, 42, 11, 17,
 	18, 19, 20, 27, 28, 43, 29, 30, 31, 32,
 	33, 34, 35, 36, 37, 38, 39, 40, 41, 14,
@@ -281,39 +345,39 @@ var yyAct = [...]int{
 	8, 12, 7, 1,
 }
 
-var yyPact = [...]int{
-	60, -1000, -1000, 23, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, 60, 60, 60, 60, 60, 60, 60, 60,
-	60, 60, 60, 60, 60, 60, 60, -15, 3, -1000,
-	-1000, -1000, 66, 66, 66, 66, -4, -4, 53, 39,
-	71, 71, -1000, 60, -1000,
+var yyPact = [...]int16{
+	60, -32768, -32768, 23, -32768, -32768, -32768, -32768, -32768, -32768,
+	-32768, -32768, 60, 60, 60, 60, 60, 60, 60, 60,
+	60, 60, 60, 60, 60, 60, 60, -15, 3, -32768,
+	-32768, -32768, 66, 66, 66, 66, -4, -4, 53, 39,
+	71, 71, -32768, 60, -32768,
 }
 
-var yyPgo = [...]int{
+var yyPgo = [...]int8{
 	0, 83, 1, 0, 82, 80, 51, 50, 35, 8,
 }
 
-var yyR1 = [...]int{
+var yyR1 = [...]int8{
 	0, 1, 3, 3, 4, 4, 4, 6, 7, 7,
 	7, 7, 8, 8, 9, 9, 5, 5, 2, 2,
 	2, 2, 2, 2, 2, 2,
 }
 
-var yyR2 = [...]int{
+var yyR2 = [...]int8{
 	0, 1, 1, 5, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 1, 1,
 	1, 1, 1, 1, 1, 1,
 }
 
-var yyChk = [...]int{
-	-1000, -1, -3, -2, 5, 4, -6, -4, -5, -7,
+var yyChk = [...]int16{
+	-32768, -1, -3, -2, 5, 4, -6, -4, -5, -7,
 	-8, -9, 21, 11, 6, 7, 8, 13, 14, 15,
 	16, 17, 18, 19, 20, 9, 10, -3, -3, -2,
 	-2, -2, -2, -2, -2, -2, -2, -2, -2, -2,
 	-2, -2, 22, 12, -3,
 }
 
-var yyDef = [...]int{
+var yyDef = [...]int8{
 	0, -2, 1, 2, 18, 19, 20, 21, 22, 23,
 	24, 25, 0, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 4,
@@ -321,17 +385,17 @@ var yyDef = [...]int{
 	16, 17, 7, 0, 3,
 }
 
-var yyTok1 = [...]int{
+var yyTok1 = [...]int8{
 	1,
 }
 
-var yyTok2 = [...]int{
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
 	22, 23,
 }
 
-var yyTok3 = [...]int{
+var yyTok3 = [...]int8{
 	0,
 }
 
@@ -374,7 +438,7 @@ func yyNewParser() yyParser {
 	return &yyParserImpl{}
 }
 
-const yyFlag = -1000
+const yyFlag = -32768
 
 func yyTokname(c int) string {
 	if c >= 1 && c-1 < len(yyToknames) {
@@ -413,9 +477,9 @@ func yyErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := yyPact[state]
+	base := int(yyPact[state])
 	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
-		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -425,13 +489,13 @@ func yyErrorMessage(state, lookAhead int) string {
 
 	if yyDef[state] == -2 {
 		i := 0
-		for yyExca[i] != -1 || yyExca[i+1] != state {
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; yyExca[i] >= 0; i += 2 {
-			tok := yyExca[i]
+			tok := int(yyExca[i])
 			if tok < TOKSTART || yyExca[i+1] == 0 {
 				continue
 			}
@@ -462,30 +526,30 @@ func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		token = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			token = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		token = yyTok3[i+0]
+		token = int(yyTok3[i+0])
 		if token == char {
-			token = yyTok3[i+1]
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = yyTok2[1] /* unknown char */
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
@@ -540,7 +604,7 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
@@ -551,8 +615,8 @@ yynewstate:
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yytoken { /* valid shift */
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
 		yyrcvr.char = -1
 		yytoken = -1
 		yyVAL = yyrcvr.lval
@@ -565,7 +629,7 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
 		if yyrcvr.char < 0 {
 			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
@@ -574,18 +638,18 @@ yydefault:
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
+			yyn = int(yyExca[xi+0])
 			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -607,10 +671,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -646,7 +710,7 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
 	// yyp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if yyp+1 >= len(yyS) {
@@ -657,16 +721,16 @@ yydefault:
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -674,141 +738,111 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line ./plurals-parser/parser.yy:68
+//line ./plurals-parser/parser.yy:69
 		{
-			yylex.(*yyLex).Result = yyDollar[1].num
+			yylex.(*yyLex).Result = yyDollar[1].node
 		}
 	case 3:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line ./plurals-parser/parser.yy:76
+//line ./plurals-parser/parser.yy:77
 		{
-			if yyDollar[1].num != 0 {
-				yyVAL.num = yyDollar[3].num
-			} else {
-				yyVAL.num = yyDollar[5].num
-			}
+			yyVAL.node = &ternaryNode{cond: yyDollar[1].node, then: yyDollar[3].node, els: yyDollar[5].node}
 		}
 	case 4:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:86
+//line ./plurals-parser/parser.yy:83
 		{
-			yyVAL.num = yyDollar[1].num % yyDollar[3].num
+			yyVAL.node = &binaryNode{op: tokMOD, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 5:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:87
+//line ./plurals-parser/parser.yy:84
 		{
-			yyVAL.num = yyDollar[1].num * yyDollar[3].num
+			yyVAL.node = &binaryNode{op: tokMULTIPLY, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 6:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:88
+//line ./plurals-parser/parser.yy:85
 		{
-			yyVAL.num = yyDollar[1].num / yyDollar[3].num
+			yyVAL.node = &binaryNode{op: tokDIVIDE, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:90
+//line ./plurals-parser/parser.yy:87
 		{
-			yyVAL.num = yyDollar[2].num
+			yyVAL.node = yyDollar[2].node
 		}
 	case 8:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:94
+//line ./plurals-parser/parser.yy:90
 		{
-			if yyDollar[1].num < yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokLT, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:102
+//line ./plurals-parser/parser.yy:91
 		{
-			if yyDollar[1].num <= yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokLE, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 10:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:110
+//line ./plurals-parser/parser.yy:92
 		{
-			if yyDollar[1].num > yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokGT, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 11:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:118
+//line ./plurals-parser/parser.yy:93
 		{
-			if yyDollar[1].num >= yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokGE, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 12:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:129
+//line ./plurals-parser/parser.yy:97
 		{
-			if yyDollar[1].num == yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokEQ, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 13:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:137
+//line ./plurals-parser/parser.yy:98
 		{
-			if yyDollar[1].num != yyDollar[3].num {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokNE, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 14:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:148
+//line ./plurals-parser/parser.yy:102
 		{
-			if yyDollar[1].num != 0 && yyDollar[3].num != 0 {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokAND, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 15:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:156
+//line ./plurals-parser/parser.yy:103
 		{
-			if yyDollar[1].num != 0 || yyDollar[3].num != 0 {
-				yyVAL.num = 1
-			} else {
-				yyVAL.num = 0
-			}
+			yyVAL.node = &binaryNode{op: tokOR, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:166
+//line ./plurals-parser/parser.yy:107
 		{
-			yyVAL.num = yyDollar[1].num + yyDollar[3].num
+			yyVAL.node = &binaryNode{op: tokADD, x: yyDollar[1].node, y: yyDollar[3].node}
 		}
 	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line ./plurals-parser/parser.yy:167
+//line ./plurals-parser/parser.yy:108
+		{
+			yyVAL.node = &binaryNode{op: tokSUBTRACT, x: yyDollar[1].node, y: yyDollar[3].node}
+		}
+	case 18:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line ./plurals-parser/parser.yy:111
 		{
-			yyVAL.num = yyDollar[1].num - yyDollar[3].num
+			yyVAL.node = numberNode(yyDollar[1].num)
 		}
 	case 19:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line ./plurals-parser/parser.yy:171
+//line ./plurals-parser/parser.yy:112
 		{
-			yyVAL.num = yylex.(*yyLex).Variables[yyDollar[1].str]
+			yyVAL.node = variableNode(yyDollar[1].str)
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -13,8 +13,9 @@ import (
 %}
 
 %union {
-    num uint64
-    str string
+    num  uint64
+    str  string
+    node node
 }
 
 %token <str> tokIDENTIFIER
@@ -40,7 +41,7 @@ import (
     tokINVALID
 ;
 
-%type <num>
+%type <node>
     unit
     expression
     if_statement
@@ -74,101 +75,41 @@ if_statement:
   expression
 | expression tokTHEN if_statement tokELSE if_statement
     {
-        if $1 != 0 {
-            $$ = $3
-        } else {
-            $$ = $5
-        }
+        $$ = &ternaryNode{cond: $1, then: $3, els: $5}
     }
 ;
 
 multiplicative:
-  expression tokMOD expression      { $$ = $1 % $3 }
-| expression tokMULTIPLY expression { $$ = $1 * $3 }
-| expression tokDIVIDE expression   { $$ = $1 / $3 }
+  expression tokMOD expression      { $$ = &binaryNode{op: tokMOD, x: $1, y: $3} }
+| expression tokMULTIPLY expression { $$ = &binaryNode{op: tokMULTIPLY, x: $1, y: $3} }
+| expression tokDIVIDE expression   { $$ = &binaryNode{op: tokDIVIDE, x: $1, y: $3} }
 
 associative: tokLPAREN if_statement tokRPAREN   { $$ = $2 }
 
 relational:
-  expression tokLT expression
-    {
-        if $1 < $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
-| expression tokLE expression
-    {
-        if $1 <= $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
-| expression tokGT expression
-    {
-        if $1 > $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
-| expression tokGE expression
-    {
-        if $1 >= $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
+  expression tokLT expression       { $$ = &binaryNode{op: tokLT, x: $1, y: $3} }
+| expression tokLE expression       { $$ = &binaryNode{op: tokLE, x: $1, y: $3} }
+| expression tokGT expression       { $$ = &binaryNode{op: tokGT, x: $1, y: $3} }
+| expression tokGE expression       { $$ = &binaryNode{op: tokGE, x: $1, y: $3} }
 ;
 
 equality:
-  expression tokEQ expression
-    {
-        if $1 == $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
-| expression tokNE expression
-    {
-        if $1 != $3 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
+  expression tokEQ expression       { $$ = &binaryNode{op: tokEQ, x: $1, y: $3} }
+| expression tokNE expression       { $$ = &binaryNode{op: tokNE, x: $1, y: $3} }
 ;
 
 logical:
-  expression tokAND expression
-    {
-        if $1 != 0 && $3 != 0 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
-| expression tokOR expression
-   {
-        if $1 != 0 || $3 != 0 {
-            $$ = 1
-        } else {
-            $$ = 0
-        }
-    }
+  expression tokAND expression      { $$ = &binaryNode{op: tokAND, x: $1, y: $3} }
+| expression tokOR expression       { $$ = &binaryNode{op: tokOR, x: $1, y: $3} }
 ;
 
 additive:
-  expression tokADD expression      { $$ = $1 + $3 }
-| expression tokSUBTRACT expression { $$ = $1 - $3 }
+  expression tokADD expression      { $$ = &binaryNode{op: tokADD, x: $1, y: $3} }
+| expression tokSUBTRACT expression { $$ = &binaryNode{op: tokSUBTRACT, x: $1, y: $3} }
 
 expression:
-  tokNUMBER
-| tokIDENTIFIER  { $$ = yylex.(*yyLex).Variables[$1] }
+  tokNUMBER      { $$ = numberNode($1) }
+| tokIDENTIFIER  { $$ = variableNode($1) }
 | associative
 | multiplicative
 | additive
@@ -180,13 +121,20 @@ expression:
 
 const eof = 0
 
+// The generated parser reads yyErrorVerbose on every syntax error, so it is
+// only ever set here, before any expression can be compiled, to keep Compile
+// and Evaluate safe for concurrent use.
+func init() {
+    yyErrorVerbose = true
+}
+
 type yyLex struct {
 	line      []byte
 	peek      rune
     idx       int
     orig      []byte
-    Result    uint64
-    Variables map[string]uint64
+    operands  bool
+    Result    node
     Err       error
 }
 
@@ -203,6 +151,17 @@ var isNumber = map[rune]bool{
     '9': true,
 }
 
+// isOperand contains the CLDR plural operands, which are only variables of
+// expressions compiled with CompileCLDR.
+var isOperand = map[rune]bool{
+    'i': true,
+    'v': true,
+    'w': true,
+    'f': true,
+    't': true,
+    'e': true,
+}
+
 var isWhitespace = map[rune]bool{
     ' ':  true,
     '\t': true,
@@ -262,7 +221,7 @@ func (x *yyLex) Lex(yylval *yySymType) (res int) {
             return tokDIVIDE
         case c == '%':
             return tokMOD
-        case c == 'n':
+        case c == 'n' || x.operands && isOperand[c]:
             yylval.str = string(c)
             return tokIDENTIFIER
 		case isWhitespace[c]:
@@ -332,27 +291,72 @@ func (x *yyLex) Error(s string) {
     x.Err = fmt.Errorf("parse error: %s\n%s\n%s\n", s, x.orig, ss.String())
 }
 
-func newLexer(line []byte, n uint64) *yyLex {
+func newLexer(line []byte, operands bool) *yyLex {
     c, size := utf8.DecodeRune(line)
     return &yyLex{
         line:      line[size:],
         peek:      c,
         idx:       -1,
         orig:      line,
-        Result:    0,
-        Variables: map[string]uint64{"n": n},
+        operands:  operands,
+        Result:    nil,
         Err:       nil,
     }
 }
 
+// Compile parses the provided Plural-Forms ternary string into an Expr that
+// can be evaluated for any value of the variable "n" without being parsed
+// again. Subexpressions that don't depend on "n" are evaluated once here.
+// As in GNU gettext, "n" is the only variable.
+//
+// Returns an error if the expression is not valid, which is a
+// *DivisionByZeroError if it divides by a constant zero and an
+// *UnderflowError if a constant subtraction underflows.
+func Compile(expression string) (*Expr, error) {
+    return compile(expression, false)
+}
+
+// CompileCLDR is like Compile but the expression may also use the CLDR
+// plural operands "i", "v", "w", "f", "t" and "e" described by Operands.
+// Such expressions are not valid Plural-Forms headers.
+func CompileCLDR(expression string) (*Expr, error) {
+    return compile(expression, true)
+}
+
+func compile(expression string, operands bool) (*Expr, error) {
+    l := newLexer([]byte(expression), operands)
+    yyParse(l)
+    if l.Err != nil {
+        return nil, l.Err
+    }
+
+    root, err := fold(l.Result)
+    if err != nil {
+        err.setExpression(expression)
+        return nil, err
+    }
+    return &Expr{expression: expression, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is not valid.
+func MustCompile(expression string) *Expr {
+    expr, err := Compile(expression)
+    if err != nil {
+        panic(err)
+    }
+    return expr
+}
+
 // Evaluate compiles and evalutes the provided Plural-Format ternary string
-// while supstituting the variable "n" with the provided value.
+// while supstituting the variable "n" with the provided value. Use Compile
+// to evaluate the same expression repeatedly.
 //
 // Returns the resulting index into the plural array and an error if an
 // error was encountered.
 func Evaluate(expression string, n uint64) (uint64, error) {
-    yyErrorVerbose = true
-    l := newLexer([]byte(expression), n)
-    yyParse(l)
-    return l.Result, l.Err
+    expr, err := Compile(expression)
+    if err != nil {
+        return 0, err
+    }
+    return expr.Eval(n)
 }
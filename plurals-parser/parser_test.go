@@ -1,7 +1,9 @@
 package pluralsparser
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -72,11 +74,340 @@ func (t *TestSuite) TestYYLex_num_Invalid() {
 	t.EqualError(lex.Err, "ERROR: strconv.ParseUint: parsing \"a\": invalid syntax. Bad number \"a\": strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
+func (t *TestSuite) TestCompile_ValidExpressions() {
+	for _, expression := range testExpressions {
+		expr, err := Compile(expression.Expression)
+		require.NoError(t.T(), err)
+		require.Equal(t.T(), expression.Expression, expr.String())
+
+		res, err := expr.Eval(expression.N)
+		require.NoError(t.T(), err)
+		require.Exactly(t.T(), expression.Truth, res, expression.Expression)
+	}
+}
+
+func (t *TestSuite) TestCompile_SyntaxError() {
+	expr, err := Compile("1>>2")
+	t.Nil(expr)
+	t.EqualError(err, "parse error: syntax error: unexpected tokGT, expecting tokIDENTIFIER or tokNUMBER or tokLPAREN\n1>>2\n  ^\n")
+
+	expr, err = Compile("x == 1")
+	t.Nil(expr)
+	t.EqualError(err, "parse error: syntax error: unexpected tokINVALID, expecting tokIDENTIFIER or tokNUMBER or tokLPAREN\nx == 1\n^\n")
+
+	// The CLDR operands are only variables for CompileCLDR.
+	expr, err = Compile("n == 1 && v == 0")
+	t.Nil(expr)
+	t.EqualError(err, "parse error: syntax error: unexpected tokINVALID, expecting tokIDENTIFIER or tokNUMBER or tokLPAREN\nn == 1 && v == 0\n          ^\n")
+
+	expr, err = CompileCLDR("x == 1")
+	t.Nil(expr)
+	t.Error(err)
+}
+
+func (t *TestSuite) TestCompileCLDR() {
+	expr, err := CompileCLDR("v == 0 && i%10 == 1 ? 0 : f%10 + t + w + e")
+	t.Require().NoError(err)
+	t.Equal(&ternaryNode{
+		cond: &binaryNode{
+			op: tokAND,
+			x:  &binaryNode{op: tokEQ, x: variableNode("v"), y: numberNode(0)},
+			y:  &binaryNode{op: tokEQ, x: &binaryNode{op: tokMOD, x: variableNode("i"), y: numberNode(10)}, y: numberNode(1)},
+		},
+		then: numberNode(0),
+		els: &binaryNode{
+			op: tokADD,
+			x: &binaryNode{
+				op: tokADD,
+				x:  &binaryNode{op: tokADD, x: &binaryNode{op: tokMOD, x: variableNode("f"), y: numberNode(10)}, y: variableNode("t")},
+				y:  variableNode("w"),
+			},
+			y: variableNode("e"),
+		},
+	}, expr.root)
+}
+
+func (t *TestSuite) TestMustCompile() {
+	t.Equal("n != 1", MustCompile("n != 1").String())
+	t.Panics(func() { MustCompile("(()") })
+}
+
+func (t *TestSuite) TestExpr_Eval_Reuse() {
+	expr, err := Compile("n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2")
+	t.Require().NoError(err)
+
+	for n, expected := range map[uint64]uint64{0: 2, 1: 0, 2: 1, 5: 2, 11: 2, 21: 0, 22: 1, 111: 2} {
+		res, err := expr.Eval(n)
+		t.NoError(err)
+		t.Equal(expected, res, n)
+	}
+}
+
+func (t *TestSuite) TestExpr_Eval_ShortCircuit() {
+	for _, expression := range []testExpression{
+		{Expression: "n != 0 && 10 / n > 2", N: 0, Truth: 0},
+		{Expression: "n == 0 || 10 % n", N: 0, Truth: 1},
+		{Expression: "n == 0 ? 0 : 10 / n", N: 0, Truth: 0},
+		{Expression: "n == 0 ? 0 : 10 / n", N: 5, Truth: 2},
+	} {
+		res, err := Evaluate(expression.Expression, expression.N)
+		t.NoError(err)
+		t.Exactly(expression.Truth, res, expression.Expression)
+	}
+}
+
+func (t *TestSuite) TestCompile_DivisionByZero() {
+	for expression, expected := range map[string]string{
+		"n % 0":                           `modulo by zero in "n % 0"`,
+		"10 / (2 - 2)":                    `division by zero in "10 / (2 - 2)"`,
+		"n ? n / (1 < 0) : 0":             `division by zero in "n ? n / (1 < 0) : 0"`,
+		"(n == 1 ? 1 : 0) % (3 > 4 && n)": `modulo by zero in "(n == 1 ? 1 : 0) % (3 > 4 && n)"`,
+	} {
+		expr, err := Compile(expression)
+		t.Nil(expr)
+		t.EqualError(err, expected)
+
+		var divErr *DivisionByZeroError
+		t.Require().True(errors.As(err, &divErr), expression)
+		t.True(divErr.Constant)
+		t.Equal(expression, divErr.Expression)
+	}
+}
+
+func (t *TestSuite) TestCompile_ConstantFolding() {
+	for expression, expected := range map[string]node{
+		"2 * 3 + 1":               numberNode(7),
+		"1 ? n : 1 / 0":           variableNode("n"),
+		"0 && n / 0":              numberNode(0),
+		"2 || n % 0":              numberNode(1),
+		"n % (10 - 5)":            &binaryNode{op: tokMOD, x: variableNode("n"), y: numberNode(5)},
+		"n != 1 ? 2 / 1 : 0 == 0": &ternaryNode{cond: &binaryNode{op: tokNE, x: variableNode("n"), y: numberNode(1)}, then: numberNode(2), els: numberNode(1)},
+	} {
+		expr, err := Compile(expression)
+		t.Require().NoError(err, expression)
+		t.Equal(expected, expr.root, expression)
+	}
+}
+
+func (t *TestSuite) TestExpr_Eval_DivisionByZero() {
+	expr, err := Compile("10 / (n - 1)")
+	t.Require().NoError(err)
+
+	res, err := expr.Eval(2)
+	t.NoError(err)
+	t.Equal(uint64(10), res)
+
+	res, err = expr.Eval(1)
+	t.Equal(uint64(0), res)
+	t.EqualError(err, `division by zero in "10 / (n - 1)" for n = 1`)
+
+	var divErr *DivisionByZeroError
+	t.Require().True(errors.As(err, &divErr))
+	t.Equal(&DivisionByZeroError{Expression: "10 / (n - 1)", Op: "/", N: 1}, divErr)
+
+	_, err = Evaluate("n % (n % 3)", 6)
+	t.EqualError(err, `modulo by zero in "n % (n % 3)" for n = 6`)
+}
+
+func (t *TestSuite) TestCompile_Underflow() {
+	for expression, expected := range map[string]string{
+		"1 - 2":             `1 - 2 underflows in "1 - 2"`,
+		"n % (3 - 5)":       `3 - 5 underflows in "n % (3 - 5)"`,
+		"n ? 0 : (1 - 2)":   `1 - 2 underflows in "n ? 0 : (1 - 2)"`,
+		"(1 - 2) / (1 - 1)": `1 - 2 underflows in "(1 - 2) / (1 - 1)"`,
+	} {
+		expr, err := Compile(expression)
+		t.Nil(expr)
+		t.EqualError(err, expected)
+
+		var underflowErr *UnderflowError
+		t.Require().True(errors.As(err, &underflowErr), expression)
+		t.True(underflowErr.Constant)
+		t.Equal(expression, underflowErr.Expression)
+	}
+}
+
+func (t *TestSuite) TestExpr_Eval_Underflow() {
+	expr, err := Compile("n > 0 ? 1 - n : 0")
+	t.Require().NoError(err)
+
+	res, err := expr.Eval(0)
+	t.NoError(err)
+	t.Equal(uint64(0), res)
+
+	res, err = expr.Eval(1)
+	t.NoError(err)
+	t.Equal(uint64(0), res)
+
+	res, err = expr.Eval(5)
+	t.Equal(uint64(0), res)
+	t.EqualError(err, `1 - 5 underflows in "n > 0 ? 1 - n : 0" for n = 5`)
+
+	var underflowErr *UnderflowError
+	t.Require().True(errors.As(err, &underflowErr))
+	t.Equal(&UnderflowError{Expression: "n > 0 ? 1 - n : 0", X: 1, Y: 5, N: 5}, underflowErr)
+
+	res, err = Evaluate("n - 1 == 0", 1)
+	t.NoError(err)
+	t.Equal(uint64(1), res)
+}
+
+func (t *TestSuite) TestParseOperands() {
+	for decimal, expected := range map[string]Operands{
+		"0":                    {},
+		"1":                    {I: 1},
+		"-1":                   {I: 1},
+		"1.0":                  {I: 1, V: 1},
+		"1.50":                 {I: 1, V: 2, W: 1, F: 50, T: 5},
+		"-0.05":                {V: 2, W: 2, F: 5, T: 5},
+		"007.3":                {I: 7, V: 1, W: 1, F: 3, T: 3},
+		"1.2e3":                {I: 1200, E: 3},
+		"1.2345c2":             {I: 123, V: 2, W: 2, F: 45, T: 45, E: 2},
+		"1.0e0":                {I: 1, V: 1},
+		"1.2e000001":           {I: 12, E: 1},
+		"1.000000000000000001": {I: 1, V: 18, W: 18, F: 1, T: 1},
+		"18446744073709551615": {I: 18446744073709551615},
+	} {
+		ops, err := ParseOperands(decimal)
+		t.NoError(err, decimal)
+		t.Equal(expected, ops, decimal)
+	}
+
+	for decimal, expected := range map[string]string{
+		"":                      `invalid decimal ""`,
+		"-":                     `invalid decimal "-"`,
+		"+1":                    `invalid decimal "+1"`,
+		" 1":                    `invalid decimal " 1"`,
+		"1.":                    `invalid decimal "1."`,
+		".5":                    `invalid decimal ".5"`,
+		"1.5.2":                 `invalid decimal "1.5.2"`,
+		"1,5":                   `invalid decimal "1,5"`,
+		"1e":                    `invalid decimal "1e"`,
+		"1e-3":                  `invalid decimal "1e-3"`,
+		"NaN":                   `invalid decimal "NaN"`,
+		"1e21":                  `decimal "1e21" is out of range`,
+		"18446744073709551616":  `decimal "18446744073709551616" is out of range`,
+		"0.0000000000000000000": `decimal "0.0000000000000000000" has more than 18 fraction digits`,
+	} {
+		_, err := ParseOperands(decimal)
+		t.EqualError(err, expected, decimal)
+	}
+}
+
+func (t *TestSuite) TestExpr_EvalOperands() {
+	for _, test := range []struct {
+		expression string
+		decimal    string
+		truth      uint64
+	}{
+		{"n != 1", "1", 0},
+		{"n != 1", "1.0", 0},
+		{"n != 1", "1.5", 1},
+		{"n != 1", "0.5", 1},
+		{"n > 1", "1.5", 1},
+		{"n < 2", "1.99", 1},
+		{"n <= 1", "1.01", 0},
+		{"n % 10 == 1", "11.0", 1},
+		{"n % 10 == 1", "11.5", 0},
+		{"n % 10 > 1", "11.5", 1},
+		{"n + n", "1.5", 3},
+		{"n + n == 3", "1.5", 1},
+		{"n - 1 > 0", "1.5", 1},
+		{"n - 1", "1.5", 0},
+		{"3 - n", "1.25", 1},
+		{"2 * n", "1.5", 2},
+		{"n / 1", "1.5", 1},
+		{"n && 1", "0.1", 1},
+		{"n || 0", "0.0", 0},
+		{"n ? 1 : 0", "0.1", 1},
+		{"i", "1.5", 1},
+		{"v", "1.50", 2},
+		{"w", "1.50", 1},
+		{"f", "1.50", 50},
+		{"t", "1.50", 5},
+		{"e", "1.5e6", 6},
+		{"i == 1 && v == 0 ? 0 : 1", "1", 0},
+		{"i == 1 && v == 0 ? 0 : 1", "1.0", 1},
+	} {
+		expr, err := CompileCLDR(test.expression)
+		t.Require().NoError(err, test.expression)
+
+		ops, err := ParseOperands(test.decimal)
+		t.Require().NoError(err, test.decimal)
+
+		res, err := expr.EvalOperands(ops)
+		t.NoError(err, test.expression)
+		t.Equal(test.truth, res, "%s for %s", test.expression, test.decimal)
+	}
+
+	expr := MustCompile("n")
+	_, err := expr.EvalOperands(Operands{V: 19})
+	t.EqualError(err, "invalid operands: v = 19 exceeds 18")
+	_, err = expr.EvalOperands(Operands{V: 2, F: 100})
+	t.EqualError(err, "invalid operands: f = 100 has more than v = 2 digits")
+
+	_, err = MustCompile("1 - n").EvalOperands(Operands{I: 1, V: 1, F: 5})
+	t.EqualError(err, `1 - 1 underflows in "1 - n" for n = 1`)
+
+	_, err = MustCompile("1 % (n - 1)").EvalOperands(Operands{I: 1, V: 1, F: 5})
+	t.EqualError(err, `modulo by zero in "1 % (n - 1)" for n = 1`)
+
+	res, err := MustCompile("n == 5").Eval(5)
+	t.NoError(err)
+	t.Equal(uint64(1), res)
+}
+
+func (t *TestSuite) TestEvaluate_Concurrent() {
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := 0; idx < 100; idx++ {
+				expression := testExpressions[idx%len(testExpressions)]
+				res, err := Evaluate(expression.Expression, expression.N)
+				t.NoError(err)
+				t.Exactly(expression.Truth, res)
+
+				_, err = Evaluate("1>>2", 0)
+				t.EqualError(err, "parse error: syntax error: unexpected tokGT, expecting tokIDENTIFIER or tokNUMBER or tokLPAREN\n1>>2\n  ^\n")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func ExampleCompile() {
+	expr, err := Compile("n != 1")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, n := range []uint64{0, 1, 2} {
+		result, _ := expr.Eval(n)
+		fmt.Printf("%d: %d\n", n, result)
+	}
+	// Output:
+	// 0: 1
+	// 1: 0
+	// 2: 1
+}
+
 func BenchmarkEvaluate(b *testing.B) {
-	for idx, expression := range testExpressions {
-		if idx > 4 {
-			break
-		}
+	for idx := 0; idx < b.N; idx++ {
+		expression := testExpressions[idx%len(testExpressions)]
 		Evaluate(expression.Expression, expression.N)
 	}
 }
+
+func BenchmarkExpr_Eval(b *testing.B) {
+	exprs := make([]*Expr, len(testExpressions))
+	for idx, expression := range testExpressions {
+		exprs[idx] = MustCompile(expression.Expression)
+	}
+
+	b.ResetTimer()
+	for idx := 0; idx < b.N; idx++ {
+		exprs[idx%len(exprs)].Eval(testExpressions[idx%len(testExpressions)].N)
+	}
+}
@@ -0,0 +1,247 @@
+package po2json
+
+// Position identifies a location within a .po file.
+type Position struct {
+	// Line is the 1-based line number.
+	Line int
+	// Column is the 1-based column, in runes.
+	Column int
+}
+
+// Previous contains the msgctxt, msgid, and msgid_plural that a message had
+// before it was updated by msgmerge. They are written as "#|" comments.
+type Previous struct {
+	Msgctxt     string
+	Msgid       string
+	MsgidPlural string
+}
+
+// Message is a single entry of a .po file.
+type Message struct {
+	Msgctxt string
+	Msgid   string
+	// MsgidPlural contains the msgid_plural of the message, if any.
+	MsgidPlural string
+	// Translation contains the msgstr of the message, if any.
+	Translation string
+	// Plurals contains the msgstr[N] of the message, if any.
+	Plurals []string
+	// TranslatorComments contains the "# " comments written by translators.
+	TranslatorComments []string
+	// ExtractedComments contains the "#." comments extracted from the source
+	// code by the developer.
+	ExtractedComments []string
+	// References contains the "#:" source code locations in which the msgid
+	// is used, e.g. "file.go:42".
+	References []string
+	// Flags contains the "#," flags of the message, e.g. "fuzzy" or "c-format".
+	Flags []string
+	// Previous contains the "#|" source strings that msgmerge recorded for a
+	// fuzzy message before the source strings changed.
+	Previous Previous
+	// Position is the position of the first msgctxt or msgid line of the
+	// message. It is the zero Position if the message was not parsed from a
+	// .po file.
+	Position Position
+}
+
+// HasFlag reports whether the message is marked with the specified flag.
+func (m Message) HasFlag(flag string) bool {
+	for _, f := range m.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a deep copy of the message.
+func (m Message) Clone() Message {
+	m.Plurals = cloneStrings(m.Plurals)
+	m.TranslatorComments = cloneStrings(m.TranslatorComments)
+	m.ExtractedComments = cloneStrings(m.ExtractedComments)
+	m.References = cloneStrings(m.References)
+	m.Flags = cloneStrings(m.Flags)
+	return m
+}
+
+// HeaderField is a single "Key: Value" line of the header.
+type HeaderField struct {
+	Key   string
+	Value string
+}
+
+// Header is the message with an empty msgctxt and msgid, whose msgstr holds
+// the metadata of the catalog.
+type Header struct {
+	Message
+	// Fields contains the key-value pairs of the msgstr in the order in which
+	// they appear.
+	Fields []HeaderField
+}
+
+// Get returns the value of the header field with the specified key.
+func (h Header) Get(key string) (string, bool) {
+	for _, field := range h.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Catalog contains the parsed contents of a .po file.
+type Catalog struct {
+	// Header contains the header entry. It is the zero Header if the file
+	// does not have one.
+	Header Header
+	// Messages contains the active messages, other than the header, in the
+	// order in which they appear in the file. Messages with the same msgctxt
+	// and msgid are merged into one.
+	Messages []*Message
+	// Obsolete contains the messages that were commented out with "#~", in
+	// the order in which they appear in the file.
+	Obsolete []*Message
+
+	index map[string]map[string]*Message
+}
+
+// NewCatalog returns an empty Catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{index: map[string]map[string]*Message{}}
+}
+
+// Add appends msg to the active messages of the catalog. A message with the
+// same msgctxt and msgid that was added earlier is shadowed by msg.
+func (c *Catalog) Add(msg *Message) {
+	if c.index == nil {
+		c.index = map[string]map[string]*Message{}
+		for _, existing := range c.Messages {
+			c.indexMessage(existing)
+		}
+	}
+
+	c.Messages = append(c.Messages, msg)
+	c.indexMessage(msg)
+}
+
+func (c *Catalog) indexMessage(msg *Message) {
+	msgctxtIndex, ok := c.index[msg.Msgctxt]
+	if !ok {
+		msgctxtIndex = map[string]*Message{}
+		c.index[msg.Msgctxt] = msgctxtIndex
+	}
+	msgctxtIndex[msg.Msgid] = msg
+}
+
+// Lookup returns the active message with the specified msgctxt and msgid. The
+// header is returned for an empty msgctxt and msgid.
+func (c *Catalog) Lookup(msgctxt string, msgid string) (*Message, bool) {
+	if len(msgctxt) == 0 && len(msgid) == 0 {
+		return &c.Header.Message, true
+	}
+
+	if c.index == nil {
+		for idx := len(c.Messages) - 1; idx >= 0; idx-- {
+			if msg := c.Messages[idx]; msg.Msgctxt == msgctxt && msg.Msgid == msgid {
+				return msg, true
+			}
+		}
+		return nil, false
+	}
+
+	msg, ok := c.index[msgctxt][msgid]
+	return msg, ok
+}
+
+// HasContext reports whether the catalog contains any active message with the
+// specified msgctxt. The empty msgctxt, which contains the header, is always
+// present.
+func (c *Catalog) HasContext(msgctxt string) bool {
+	if len(msgctxt) == 0 {
+		return true
+	}
+
+	if c.index == nil {
+		for _, msg := range c.Messages {
+			if msg.Msgctxt == msgctxt {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, ok := c.index[msgctxt]
+	return ok
+}
+
+// Map returns the active messages of the catalog keyed by msgctxt and then by
+// msgid. This is the structure that is returned by LoadBytes. The header
+// fields are stored directly in the object of the empty msgctxt and msgid.
+//
+// The returned map does not share any memory with the catalog.
+func (c *Catalog) Map() map[string]interface{} {
+	header := messageMap(&c.Header.Message, false)
+	for _, field := range c.Header.Fields {
+		header[field.Key] = field.Value
+	}
+
+	messages := map[string]interface{}{
+		"": map[string]interface{}{"": header},
+	}
+	for _, msg := range c.Messages {
+		msgctxtObj, ok := messages[msg.Msgctxt].(map[string]interface{})
+		if !ok {
+			msgctxtObj = map[string]interface{}{}
+			messages[msg.Msgctxt] = msgctxtObj
+		}
+		msgctxtObj[msg.Msgid] = messageMap(msg, true)
+	}
+
+	return messages
+}
+
+// messageMap converts msg into the object stored under its msgid by Map.
+func messageMap(msg *Message, withTranslation bool) map[string]interface{} {
+	msgidObj := map[string]interface{}{}
+	if withTranslation && len(msg.Translation) > 0 {
+		msgidObj["translation"] = msg.Translation
+	}
+	if len(msg.MsgidPlural) > 0 {
+		msgidObj["msgidPlural"] = msg.MsgidPlural
+	}
+	for key, values := range map[string][]string{
+		"plurals":            msg.Plurals,
+		"translatorComments": msg.TranslatorComments,
+		"extractedComments":  msg.ExtractedComments,
+		"references":         msg.References,
+		"flags":              msg.Flags,
+	} {
+		if len(values) > 0 {
+			msgidObj[key] = cloneStrings(values)
+		}
+	}
+
+	previous := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"msgctxt":     msg.Previous.Msgctxt,
+		"msgid":       msg.Previous.Msgid,
+		"msgidPlural": msg.Previous.MsgidPlural,
+	} {
+		if len(value) > 0 {
+			previous[key] = value
+		}
+	}
+	if len(previous) > 0 {
+		msgidObj["previous"] = previous
+	}
+
+	return msgidObj
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
@@ -0,0 +1,142 @@
+package po2json
+
+func (t *TestSuite) TestParseFile_Catalog() {
+	catalog, err := ParseFile(poFilePath)
+	t.NoError(err)
+
+	t.Equal([]HeaderField{
+		{Key: "MIME-Version", Value: "1.0"},
+		{Key: "Content-Type", Value: "text/plain; charset=UTF-8"},
+		{Key: "Content-Transfer-Encoding", Value: "8bit"},
+		{Key: "Language", Value: "ru"},
+		{Key: "Plural-Forms", Value: "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"},
+	}, catalog.Header.Fields)
+	t.Equal(Position{Line: 1, Column: 1}, catalog.Header.Position)
+
+	language, ok := catalog.Header.Get("Language")
+	t.True(ok)
+	t.Equal("ru", language)
+	_, ok = catalog.Header.Get("Last-Translator")
+	t.False(ok)
+
+	msgids := []string{}
+	for _, msg := range catalog.Messages {
+		msgids = append(msgids, msg.Msgctxt+"|"+msg.Msgid)
+	}
+	t.Equal([]string{
+		"|%d user likes this.",
+		"This is some context about the string.|Accept language %{accept_language} was rejected",
+		"Button label|Log in",
+		"Dialog title|Log in",
+		"|One piggy went to the market.",
+		"Context with plural|One piggy went to the market.",
+		"|#This is a message with a # sign.",
+	}, msgids)
+	t.Len(catalog.Obsolete, 2)
+}
+
+func (t *TestSuite) TestCatalog_Lookup() {
+	catalog, err := ParseFile(poFilePath)
+	t.NoError(err)
+
+	msg, ok := catalog.Lookup("Button label", "Log in")
+	t.True(ok)
+	t.Equal("Войти", msg.Translation)
+
+	msg, ok = catalog.Lookup("", "")
+	t.True(ok)
+	t.Equal(&catalog.Header.Message, msg)
+
+	_, ok = catalog.Lookup("Button label", "Log out")
+	t.False(ok)
+
+	t.True(catalog.HasContext(""))
+	t.True(catalog.HasContext("Button label"))
+	t.False(catalog.HasContext("Butt"))
+}
+
+func (t *TestSuite) TestCatalog_LookupWithoutIndex() {
+	catalog := &Catalog{Messages: []*Message{
+		{Msgctxt: "Button label", Msgid: "Log in", Translation: "Войти"},
+	}}
+
+	msg, ok := catalog.Lookup("Button label", "Log in")
+	t.True(ok)
+	t.Equal("Войти", msg.Translation)
+	t.True(catalog.HasContext("Button label"))
+	t.False(catalog.HasContext("Dialog title"))
+
+	catalog.Add(&Message{Msgctxt: "Dialog title", Msgid: "Log in", Translation: "Вход в систему"})
+	msg, ok = catalog.Lookup("Button label", "Log in")
+	t.True(ok)
+	t.Equal("Войти", msg.Translation)
+	t.True(catalog.HasContext("Dialog title"))
+}
+
+func (t *TestSuite) TestParseBytes_MergesDuplicates() {
+	catalog, err := ParseBytes([]byte(`
+# First comment.
+#, c-format
+msgid "Log in"
+msgstr "Войти"
+
+#, fuzzy, c-format
+# Second comment.
+msgid "Log in"
+msgid_plural "Log ins"
+msgstr[0] "Вход"
+`))
+	t.NoError(err)
+	t.Equal([]*Message{
+		{
+			Msgid:              "Log in",
+			MsgidPlural:        "Log ins",
+			Translation:        "Войти",
+			Plurals:            []string{"Вход"},
+			TranslatorComments: []string{"First comment.", "Second comment."},
+			Flags:              []string{"c-format"},
+			Position:           Position{Line: 4, Column: 1},
+		},
+	}, catalog.Messages)
+}
+
+func (t *TestSuite) TestParseBytes_NoHeader() {
+	catalog, err := ParseBytes([]byte(`
+msgid "Log in"
+msgstr "Войти"
+
+# Trailing comment.
+`))
+	t.NoError(err)
+	t.Equal(Header{}, catalog.Header)
+	t.Len(catalog.Messages, 1)
+	t.Equal(map[string]interface{}{
+		"": map[string]interface{}{
+			"":       map[string]interface{}{},
+			"Log in": map[string]interface{}{"translation": "Войти"},
+		},
+	}, catalog.Map())
+}
+
+func (t *TestSuite) TestCatalog_MapDoesNotShareMemory() {
+	catalog, err := ParseFile(poFilePath)
+	t.NoError(err)
+
+	messages := catalog.Map()
+	references := messages["Button label"].(map[string]interface{})["Log in"].(map[string]interface{})["references"].([]string)
+	references[0] = "changed"
+
+	msg, ok := catalog.Lookup("Button label", "Log in")
+	t.True(ok)
+	t.Equal("src/login.go:42", msg.References[0])
+}
+
+func (t *TestSuite) TestMessage_Clone() {
+	msg := Message{Msgid: "Log in", Flags: []string{"fuzzy"}}
+	clone := msg.Clone()
+	clone.Flags[0] = "c-format"
+
+	t.True(msg.HasFlag("fuzzy"))
+	t.False(msg.HasFlag("c-format"))
+	t.True(clone.HasFlag("c-format"))
+}
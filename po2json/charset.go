@@ -0,0 +1,155 @@
+package po2json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	regexCharset = regexp.MustCompile(`Content-Type:[^\\"]*charset=([-\w.:+()]+)`)
+
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16BE = []byte{0xfe, 0xff}
+	bomUTF16LE = []byte{0xff, 0xfe}
+
+	// charsetAliases maps the charset names that GNU gettext accepts, but that
+	// are not registered with IANA, to the IANA name of the same encoding.
+	charsetAliases = map[string]string{
+		"ASCII":  "US-ASCII",
+		"CP1250": "windows-1250",
+		"CP1251": "windows-1251",
+		"CP1252": "windows-1252",
+		"CP1253": "windows-1253",
+		"CP1254": "windows-1254",
+		"CP1255": "windows-1255",
+		"CP1256": "windows-1256",
+		"CP1257": "windows-1257",
+		"CP1258": "windows-1258",
+		"CP932":  "Shift_JIS",
+		"CP949":  "EUC-KR",
+		"CP950":  "Big5",
+		"GB2312": "GBK",
+	}
+)
+
+// newUTF8Reader returns a reader that yields the contents of r transcoded to
+// UTF-8.
+//
+// A byte order mark at the start of r selects UTF-8 or UTF-16. Otherwise,
+// the first entry of the file, which is normally the header, is buffered and
+// the charset named in its Content-Type is used to decode the whole file.
+// Files without a charset are assumed to be UTF-8.
+func (l *loader) newUTF8Reader(r io.Reader) (*bufio.Reader, error) {
+	reader := bufio.NewReader(r)
+
+	bom, _ := reader.Peek(len(bomUTF8))
+	switch {
+	case bytes.HasPrefix(bom, bomUTF8):
+		if _, err := reader.Discard(len(bomUTF8)); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case bytes.HasPrefix(bom, bomUTF16BE), bytes.HasPrefix(bom, bomUTF16LE):
+		decoder := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		return bufio.NewReader(transform.NewReader(reader, decoder)), nil
+	}
+
+	head := bytes.Buffer{}
+	var charset []byte
+	var charsetPos position
+	inEntry := false
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		head.Write(line)
+
+		if loc := regexCharset.FindSubmatchIndex(line); loc != nil && charset == nil {
+			charset = line[loc[2]:loc[3]]
+			charsetPos = position{
+				line:   lineNumber,
+				column: utf8.RuneCount(line[:loc[2]]) + 1,
+				text:   string(bytes.TrimSuffix(line, []byte("\n"))),
+			}
+		}
+
+		// The first entry ends at the first empty line that follows a
+		// keyword or string line.
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 && inEntry || err == io.EOF {
+			break
+		}
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			inEntry = true
+		}
+	}
+
+	rest := io.MultiReader(&head, reader)
+	if charset == nil {
+		return bufio.NewReader(rest), nil
+	}
+
+	enc, err := lookupCharset(string(charset))
+	if err != nil {
+		parseErr := l.errorf(charsetPos, "Invalid .po file. Found unsupported charset %q", charset)
+		parseErr.Err = err
+		return nil, parseErr
+	}
+	if enc == nil {
+		return bufio.NewReader(rest), nil
+	}
+
+	return bufio.NewReader(transform.NewReader(rest, enc.NewDecoder())), nil
+}
+
+// headerEncoding returns the encoding named by the charset of the
+// Content-Type of header. A nil encoding is returned if header has no
+// charset or if the charset doesn't need to be transcoded from UTF-8.
+func headerEncoding(header Header) (encoding.Encoding, error) {
+	contentType, _ := header.Get(HeaderContentType)
+	matches := regexCharset.FindStringSubmatch(HeaderContentType + ":" + contentType)
+	if matches == nil {
+		return nil, nil
+	}
+
+	enc, err := lookupCharset(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("charset %q: %w", matches[1], err)
+	}
+	return enc, nil
+}
+
+// lookupCharset returns the encoding with the given name. A nil encoding is
+// returned for charsets that don't need to be transcoded to UTF-8.
+func lookupCharset(name string) (encoding.Encoding, error) {
+	name = strings.ToUpper(name)
+	switch name {
+	// "CHARSET" is the placeholder that xgettext writes into .pot files.
+	case "UTF-8", "CHARSET", "US-ASCII", "ASCII":
+		return nil, nil
+	}
+
+	if alias, ok := charsetAliases[name]; ok {
+		name = alias
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, ErrUnsupportedCharset
+	}
+	return enc, nil
+}
@@ -0,0 +1,85 @@
+package po2json
+
+import (
+	"errors"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encodePO(enc encoding.Encoding, charset string, msgid string, msgstr string) []byte {
+	fileContents := `# Translator comment
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=` + charset + `\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "` + msgid + `"
+msgstr "` + msgstr + `"
+`
+	encoded, err := enc.NewEncoder().Bytes([]byte(fileContents))
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetKOI8R() {
+	poJSON, err := LoadBytes(encodePO(charmap.KOI8R, "KOI8-R", "Log in", "Войти"))
+	t.NoError(err)
+	t.Equal("Войти", poJSON[""].(map[string]interface{})["Log in"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetISO88591() {
+	poJSON, err := LoadBytes(encodePO(charmap.ISO8859_1, "ISO-8859-1", "Open", "Öffnen"))
+	t.NoError(err)
+	t.Equal("Öffnen", poJSON[""].(map[string]interface{})["Open"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetGNUAlias() {
+	poJSON, err := LoadBytes(encodePO(charmap.Windows1251, "CP1251", "Log in", "Войти"))
+	t.NoError(err)
+	t.Equal("Войти", poJSON[""].(map[string]interface{})["Log in"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetShiftJIS() {
+	// The second byte of "表" is 0x5c, which must not be read as a backslash.
+	poJSON, err := LoadBytes(encodePO(japanese.ShiftJIS, "Shift_JIS", "Display", "表示"))
+	t.NoError(err)
+	t.Equal("表示", poJSON[""].(map[string]interface{})["Display"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetPlaceholder() {
+	poJSON, err := LoadBytes(encodePO(encoding.Nop, "CHARSET", "Log in", "Войти"))
+	t.NoError(err)
+	t.Equal("Войти", poJSON[""].(map[string]interface{})["Log in"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetUTF8BOM() {
+	fileContents := append([]byte{0xef, 0xbb, 0xbf}, encodePO(encoding.Nop, "UTF-8", "Log in", "Войти")...)
+	poJSON, err := LoadBytes(fileContents)
+	t.NoError(err)
+	t.Equal("Войти", poJSON[""].(map[string]interface{})["Log in"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetUTF16() {
+	for _, endianness := range []unicode.Endianness{unicode.LittleEndian, unicode.BigEndian} {
+		enc := unicode.UTF16(endianness, unicode.UseBOM)
+		poJSON, err := LoadBytes(encodePO(enc, "UTF-16", "Log in", "Войти"))
+		t.NoError(err)
+		t.Equal("Войти", poJSON[""].(map[string]interface{})["Log in"].(map[string]interface{})["translation"])
+	}
+}
+
+func (t *TestSuite) TestLoadBytes_CharsetUnknown() {
+	poJSON, err := LoadBytes(encodePO(encoding.Nop, "bogus", "Log in", "Войти"))
+	t.EqualError(err, `line 4, column 36: Invalid .po file. Found unsupported charset "bogus": ianaindex: invalid encoding name`)
+	t.Nil(poJSON)
+
+	var parseErr *ParseError
+	t.True(errors.As(err, &parseErr))
+	t.Equal(4, parseErr.Line)
+	t.Equal(`"Content-Type: text/plain; charset=bogus\n"`, parseErr.Text)
+}
@@ -0,0 +1,282 @@
+package po2json
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// DefaultWidth is the page width at which GNU gettext wraps strings by default.
+const DefaultWidth = 79
+
+// An Encoder writes catalogs to an output stream in the .po format.
+type Encoder struct {
+	w     io.Writer
+	width int
+}
+
+// NewEncoder returns a new Encoder that writes to w. Strings are wrapped at
+// DefaultWidth columns.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, width: DefaultWidth}
+}
+
+// SetWidth sets the page width at which strings are wrapped. A width of zero
+// or less disables wrapping, as msgcat --no-wrap does; strings are still split
+// after embedded newlines.
+func (e *Encoder) SetWidth(width int) {
+	e.width = width
+}
+
+// Encode writes c to the stream in the format used by GNU gettext: the
+// header, followed by the active messages and the obsolete messages in order.
+// Each message is written with its comments, flags, references, previous
+// strings, msgctxt, msgid, msgid_plural, and msgstr, separated by blank
+// lines. A canonical file that was written by GNU gettext with the same page
+// width is reproduced byte for byte.
+//
+// If the header has fields but no msgstr, the msgstr is built from the fields.
+//
+// The output is encoded in the charset of the Content-Type header, so that a
+// file that was loaded from a charset other than UTF-8 is written back in
+// that charset. An error is returned if the charset is not supported or if a
+// string can't be represented in it.
+func (e *Encoder) Encode(c *Catalog) error {
+	enc, err := headerEncoding(c.Header)
+	if err != nil {
+		return err
+	}
+
+	w := e.w
+	var tw *transform.Writer
+	if enc != nil {
+		tw = transform.NewWriter(w, enc.NewEncoder())
+		w = tw
+	}
+	bw := bufio.NewWriter(w)
+
+	first := true
+	writeMessage := func(msg *Message, obsolete bool) {
+		if !first {
+			bw.WriteByte('\n')
+		}
+		first = false
+		e.writeMessage(bw, msg, obsolete)
+	}
+
+	if header := c.Header; hasHeader(header) {
+		if len(header.Translation) == 0 && len(header.Fields) > 0 {
+			ss := strings.Builder{}
+			for _, field := range header.Fields {
+				ss.WriteString(field.Key + ": " + field.Value + "\n")
+			}
+			header.Translation = ss.String()
+		}
+		writeMessage(&header.Message, false)
+	}
+	for _, msg := range c.Messages {
+		writeMessage(msg, false)
+	}
+	for _, msg := range c.Obsolete {
+		writeMessage(msg, true)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if tw != nil {
+		// Close flushes the input that the encoder has buffered.
+		return tw.Close()
+	}
+	return nil
+}
+
+func hasHeader(header Header) bool {
+	return header.Position.Line > 0 ||
+		len(header.Translation) > 0 ||
+		len(header.Fields) > 0 ||
+		len(header.TranslatorComments) > 0 ||
+		len(header.ExtractedComments) > 0 ||
+		len(header.Flags) > 0
+}
+
+func (e *Encoder) writeMessage(bw *bufio.Writer, msg *Message, obsolete bool) {
+	for _, comment := range msg.TranslatorComments {
+		writeComment(bw, "#", comment)
+	}
+	for _, comment := range msg.ExtractedComments {
+		writeComment(bw, "#.", comment)
+	}
+	e.writeReferences(bw, msg.References)
+	if len(msg.Flags) > 0 {
+		bw.WriteString("#, " + strings.Join(msg.Flags, ", ") + "\n")
+	}
+
+	prefix := ""
+	previousPrefix := "#| "
+	if obsolete {
+		prefix = "#~ "
+		previousPrefix = "#~| "
+	}
+
+	if len(msg.Previous.Msgctxt) > 0 {
+		e.writeString(bw, previousPrefix, "msgctxt", msg.Previous.Msgctxt)
+	}
+	if len(msg.Previous.Msgid) > 0 {
+		e.writeString(bw, previousPrefix, "msgid", msg.Previous.Msgid)
+	}
+	if len(msg.Previous.MsgidPlural) > 0 {
+		e.writeString(bw, previousPrefix, "msgid_plural", msg.Previous.MsgidPlural)
+	}
+
+	if len(msg.Msgctxt) > 0 {
+		e.writeString(bw, prefix, "msgctxt", msg.Msgctxt)
+	}
+	e.writeString(bw, prefix, "msgid", msg.Msgid)
+	if len(msg.MsgidPlural) == 0 {
+		e.writeString(bw, prefix, "msgstr", msg.Translation)
+		return
+	}
+
+	e.writeString(bw, prefix, "msgid_plural", msg.MsgidPlural)
+	plurals := msg.Plurals
+	if len(plurals) == 0 {
+		plurals = []string{""}
+	}
+	for idx, plural := range plurals {
+		e.writeString(bw, prefix, "msgstr["+strconv.Itoa(idx)+"]", plural)
+	}
+}
+
+func writeComment(bw *bufio.Writer, marker string, comment string) {
+	bw.WriteString(marker)
+	if len(comment) > 0 {
+		bw.WriteString(" " + comment)
+	}
+	bw.WriteByte('\n')
+}
+
+// writeReferences writes the "#:" comments, filling each line with as many
+// references as fit within the page width.
+func (e *Encoder) writeReferences(bw *bufio.Writer, references []string) {
+	if len(references) == 0 {
+		return
+	}
+
+	width := e.width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	bw.WriteString("#:")
+	column := 2
+	for _, reference := range references {
+		length := 1 + utf8.RuneCountInString(reference)
+		if column > 2 && column+length > width {
+			bw.WriteString("\n#:")
+			column = 2
+		}
+		bw.WriteString(" " + reference)
+		column += length
+	}
+	bw.WriteByte('\n')
+}
+
+// writeString writes the keyword followed by s as one or more quoted lines.
+// Like GNU gettext, s is split after each embedded newline and wrapped at the
+// page width, and an empty first line is written if s spans several lines.
+func (e *Encoder) writeString(bw *bufio.Writer, prefix string, keyword string, s string) {
+	width := math.MaxInt32
+	if e.width > 0 {
+		// Leave room for the prefix and the opening and closing quotes.
+		width = e.width - utf8.RuneCountInString(prefix) - 2
+	}
+	firstColumn := len(keyword) + 1
+
+	portions := splitPortions(s)
+	lines := wrapPortion(portions[0], width, firstColumn)
+	if len(portions) == 1 && len(lines) == 1 {
+		bw.WriteString(prefix + keyword + ` "` + lines[0] + "\"\n")
+		return
+	}
+
+	bw.WriteString(prefix + keyword + " \"\"\n")
+	for _, portion := range portions {
+		for _, line := range wrapPortion(portion, width, 0) {
+			bw.WriteString(prefix + `"` + line + "\"\n")
+		}
+	}
+}
+
+// splitPortions splits s after each newline. The result contains at least one
+// portion.
+func splitPortions(s string) []string {
+	portions := strings.SplitAfter(s, "\n")
+	if len(portions) > 1 && len(portions[len(portions)-1]) == 0 {
+		portions = portions[:len(portions)-1]
+	}
+	return portions
+}
+
+// wrapPortion escapes portion and breaks it into lines of at most width
+// columns, the first of which starts at startColumn. Lines are only broken at
+// the opportunities allowed by the Unicode line breaking algorithm, so a line
+// may be longer than width if it can't be broken.
+func wrapPortion(portion string, width int, startColumn int) []string {
+	escaped := strings.Builder{}
+	// prohibited records the offsets within escaped before which the line
+	// must not be broken because they are in the middle of escape sequences.
+	prohibited := map[int]bool{}
+	for idx := 0; idx < len(portion); idx++ {
+		quoted := Quote(portion[idx : idx+1])
+		quoted = quoted[1 : len(quoted)-1]
+		if len(quoted) > 1 {
+			prohibited[escaped.Len()+1] = true
+		}
+		escaped.WriteString(quoted)
+	}
+	text := escaped.String()
+
+	// Don't break immediately before the newline at the end.
+	if strings.HasSuffix(portion, "\n") {
+		prohibited[len(text)-2] = true
+	}
+
+	breaks := breakOpportunities(text)
+	for offset := range prohibited {
+		breaks[offset] = false
+	}
+
+	lines := []string{}
+	lineStart := 0
+	// lastBreak is the offset of the last opportunity at which the current
+	// line may be broken, lastColumn is the column at which it lies, and
+	// pieceWidth is the width of the text that follows it.
+	lastBreak := -1
+	lastColumn := startColumn
+	pieceWidth := 0
+	for offset, r := range text {
+		if breaks[offset] {
+			if lastBreak >= 0 && lastColumn+pieceWidth > width {
+				lines = append(lines, text[lineStart:lastBreak])
+				lineStart = lastBreak
+				lastColumn = 0
+			}
+			lastBreak = offset
+			lastColumn += pieceWidth
+			pieceWidth = 0
+		}
+		pieceWidth += runeWidth(r)
+	}
+	if lastBreak >= 0 && lastColumn+pieceWidth > width {
+		lines = append(lines, text[lineStart:lastBreak])
+		lineStart = lastBreak
+	}
+
+	return append(lines, text[lineStart:])
+}
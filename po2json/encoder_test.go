@@ -0,0 +1,166 @@
+package po2json
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const canonicalPoFilePath = "../testdata/canonical.po"
+
+func (t *TestSuite) encode(catalog *Catalog, width int) string {
+	buf := bytes.Buffer{}
+	encoder := NewEncoder(&buf)
+	encoder.SetWidth(width)
+	t.Require().NoError(encoder.Encode(catalog))
+	return buf.String()
+}
+
+func (t *TestSuite) TestEncode_RoundTrip() {
+	contents, err := ioutil.ReadFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	catalog, err := ParseBytes(contents)
+	t.Require().NoError(err)
+
+	t.Equal(string(contents), t.encode(catalog, DefaultWidth))
+}
+
+func (t *TestSuite) TestEncode_RoundTripNoWrap() {
+	catalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	noWrap := t.encode(catalog, 0)
+	t.Contains(noWrap, "\n"+`"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"`+"\n")
+	t.Contains(noWrap, "\n"+`msgid "The server could not process your request because the request was malformed or too large. Please try again later."`+"\n")
+	t.Contains(noWrap, "\nmsgid \"\"\n\"First line\\n\"\n")
+
+	reparsed, err := ParseString(noWrap)
+	t.Require().NoError(err)
+	t.Equal(catalog.Map(), reparsed.Map())
+	t.Require().Len(reparsed.Obsolete, len(catalog.Obsolete))
+	for idx, msg := range reparsed.Obsolete {
+		msg.Position = catalog.Obsolete[idx].Position
+		t.Equal(catalog.Obsolete[idx], msg)
+	}
+}
+
+func (t *TestSuite) TestEncode_Width() {
+	catalog := NewCatalog()
+	catalog.Add(&Message{
+		Msgid:       "one two three four five six",
+		Translation: `a "quoted" \ string`,
+		References:  []string{"a.go:1", "b.go:2", "c.go:3"},
+	})
+
+	t.Equal(`#: a.go:1
+#: b.go:2
+#: c.go:3
+msgid ""
+"one two "
+"three "
+"four five "
+"six"
+msgstr ""
+"a \"quoted"
+"\" \\ "
+"string"
+`, t.encode(catalog, 12))
+}
+
+func (t *TestSuite) TestEncode_Header() {
+	catalog := NewCatalog()
+	catalog.Header.Flags = []string{"fuzzy"}
+	catalog.Header.Fields = []HeaderField{
+		{Key: HeaderLanguage, Value: "de"},
+		{Key: HeaderContentType, Value: "text/plain; charset=UTF-8"},
+	}
+	catalog.Add(&Message{Msgctxt: "menu", Msgid: "File", MsgidPlural: "Files"})
+
+	t.Equal(`#, fuzzy
+msgid ""
+msgstr ""
+"Language: de\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+
+msgctxt "menu"
+msgid "File"
+msgid_plural "Files"
+msgstr[0] ""
+`, t.encode(catalog, DefaultWidth))
+}
+
+func (t *TestSuite) TestEncode_NoHeader() {
+	catalog := NewCatalog()
+	catalog.Add(&Message{Msgid: "Yes", Translation: "Ja", TranslatorComments: []string{""}})
+
+	t.Equal("#\nmsgid \"Yes\"\nmsgstr \"Ja\"\n", t.encode(catalog, DefaultWidth))
+}
+
+func (t *TestSuite) TestEncode_Charset() {
+	for _, fileContents := range [][]byte{
+		encodePO(charmap.ISO8859_1, "ISO-8859-1", "Open", "Öffnen"),
+		encodePO(charmap.KOI8R, "KOI8-R", "Log in", "Войти"),
+		encodePO(charmap.Windows1251, "CP1251", "Log in", "Войти"),
+	} {
+		catalog, err := ParseBytes(fileContents)
+		t.Require().NoError(err)
+		t.Equal(string(fileContents), t.encode(catalog, DefaultWidth))
+	}
+}
+
+func (t *TestSuite) TestEncode_CharsetUnrepresentable() {
+	catalog := NewCatalog()
+	catalog.Header.Fields = []HeaderField{{Key: HeaderContentType, Value: "text/plain; charset=ISO-8859-1"}}
+	catalog.Add(&Message{Msgid: "Log in", Translation: "Войти"})
+
+	err := NewEncoder(&bytes.Buffer{}).Encode(catalog)
+	t.EqualError(err, "encoding: rune not supported by encoding.")
+}
+
+func (t *TestSuite) TestEncode_UnsupportedCharset() {
+	catalog := NewCatalog()
+	catalog.Header.Fields = []HeaderField{{Key: HeaderContentType, Value: "text/plain; charset=UTF-7"}}
+
+	err := NewEncoder(&bytes.Buffer{}).Encode(catalog)
+	t.EqualError(err, `charset "UTF-7": charset is not supported`)
+	t.True(errors.Is(err, ErrUnsupportedCharset))
+}
+
+func (t *TestSuite) TestEncode_WriteError() {
+	encoder := NewEncoder(errorWriter{})
+	err := encoder.Encode(&Catalog{Messages: []*Message{{Msgid: "Yes"}}})
+	t.EqualError(err, "write failed")
+}
+
+func (t *TestSuite) TestBreakOpportunities() {
+	for s, expected := range map[string][]string{
+		"one two  three":  {"one ", "two  ", "three"},
+		"n%10<=4 && n":    {"n", "%10<=4 ", "&& ", "n"},
+		"100!=11 ? 0 : n": {"100!", "=11 ? ", "0 : ", "n"},
+		"(n) well-known":  {"(n) ", "well-", "known"},
+		"and/or 1/2":      {"and/", "or ", "1/2"},
+		"«слово» и слово": {"«слово» ", "и ", "слово"},
+		"日本語。":            {"日", "本", "語。"},
+		`say \"hi\" now`:  {"say ", `\"hi`, `\" `, "now"},
+		"e\u0301t\u00e9":  {"e\u0301t\u00e9"},
+	} {
+		actual := []string{}
+		start := 0
+		for idx, ok := range breakOpportunities(s) {
+			if ok {
+				actual = append(actual, s[start:idx])
+				start = idx
+			}
+		}
+		t.Equal(expected, append(actual, s[start:]), s)
+	}
+}
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
@@ -0,0 +1,139 @@
+package po2json
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedCharset is the underlying error of a ParseError for a charset
+// that is recognized but can't be decoded.
+var ErrUnsupportedCharset = errors.New("charset is not supported")
+
+// ErrHeaderFieldNotFound is returned when a header field is requested that the
+// header does not contain.
+var ErrHeaderFieldNotFound = errors.New("header field not found")
+
+// ErrPluralFormsPlaceholder is returned by ParsePluralForms for the
+// "nplurals=INTEGER; plural=EXPRESSION;" placeholder that xgettext and msginit
+// write into .pot files. GNU gettext uses its default rule for such catalogs.
+var ErrPluralFormsPlaceholder = errors.New("template placeholder")
+
+// ParseError describes a problem encountered while parsing a .po file.
+type ParseError struct {
+	// File is the name of the file being parsed. It is empty if the .po file
+	// was not loaded from a file.
+	File string
+	// Line is the 1-based line number of the offending line.
+	Line int
+	// Column is the 1-based column, in runes, at which the problem was found.
+	Column int
+	// Text is the text of the offending line.
+	Text string
+	// Expected contains the keywords that would have been valid in place of
+	// the offending one. It is empty if the problem is not an unexpected
+	// keyword.
+	Expected []string
+	// Message describes the problem.
+	Message string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the position and description of the problem.
+func (e *ParseError) Error() string {
+	ss := strings.Builder{}
+	if len(e.File) > 0 {
+		ss.WriteString(fmt.Sprintf("%s:%d:%d: ", e.File, e.Line, e.Column))
+	} else {
+		ss.WriteString(fmt.Sprintf("line %d, column %d: ", e.Line, e.Column))
+	}
+
+	ss.WriteString(e.Message)
+	if e.Err != nil {
+		ss.WriteString(": ")
+		ss.WriteString(e.Err.Error())
+	}
+	return ss.String()
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// position identifies a location within the .po file being parsed.
+type position struct {
+	line   int
+	column int
+	text   string
+}
+
+// position returns the position of the byte at offset within line. line must
+// be a suffix of the line that is currently being parsed, which is the case for
+// the remainder of "#~" and "#|" lines.
+func (l *loader) position(line []byte, offset int) position {
+	byteOffset := len(l.text) - len(line) + offset
+	return position{
+		line:   l.line,
+		column: utf8.RuneCount(l.text[:byteOffset]) + 1,
+		text:   string(l.text),
+	}
+}
+
+// errorf returns a ParseError located at pos.
+func (l *loader) errorf(pos position, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		File:    l.file,
+		Line:    pos.line,
+		Column:  pos.column,
+		Text:    pos.text,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// MOError describes a problem encountered while parsing a .mo file.
+type MOError struct {
+	// File is the name of the file being parsed. It is empty if the .mo file
+	// was not loaded from a file.
+	File string
+	// Offset is the byte offset within the file at which the problem was
+	// found.
+	Offset int
+	// Message describes the problem.
+	Message string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the offset and description of the problem.
+func (e *MOError) Error() string {
+	ss := strings.Builder{}
+	if len(e.File) > 0 {
+		ss.WriteString(fmt.Sprintf("%s: offset %d: ", e.File, e.Offset))
+	} else {
+		ss.WriteString(fmt.Sprintf("offset %d: ", e.Offset))
+	}
+
+	ss.WriteString(e.Message)
+	if e.Err != nil {
+		ss.WriteString(": ")
+		ss.WriteString(e.Err.Error())
+	}
+	return ss.String()
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *MOError) Unwrap() error {
+	return e.Err
+}
+
+// errorf returns a MOError located at offset.
+func (mr *moReader) errorf(offset int, format string, args ...interface{}) *MOError {
+	return &MOError{
+		File:    mr.file,
+		Offset:  offset,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,239 @@
+package po2json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Well-known header keys.
+const (
+	HeaderProjectIDVersion        = "Project-Id-Version"
+	HeaderReportMsgidBugsTo       = "Report-Msgid-Bugs-To"
+	HeaderPOTCreationDate         = "POT-Creation-Date"
+	HeaderPORevisionDate          = "PO-Revision-Date"
+	HeaderLastTranslator          = "Last-Translator"
+	HeaderLanguageTeam            = "Language-Team"
+	HeaderLanguage                = "Language"
+	HeaderMIMEVersion             = "MIME-Version"
+	HeaderContentType             = "Content-Type"
+	HeaderContentTransferEncoding = "Content-Transfer-Encoding"
+	HeaderPluralForms             = "Plural-Forms"
+	HeaderGenerator               = "X-Generator"
+)
+
+// headerDateLayouts contains the layouts of the dates written by xgettext,
+// msginit, msgmerge, and common translation editors.
+var headerDateLayouts = []string{
+	"2006-01-02 15:04-0700",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04Z0700",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// PluralForms contains the parsed value of the Plural-Forms header.
+type PluralForms struct {
+	// NPlurals is the number of plural forms.
+	NPlurals int
+	// Plural is the C expression that selects the plural form for n.
+	Plural string
+}
+
+// ProjectIDVersion returns the value of the Project-Id-Version header.
+func (h Header) ProjectIDVersion() string {
+	value, _ := h.Get(HeaderProjectIDVersion)
+	return value
+}
+
+// ReportMsgidBugsTo returns the value of the Report-Msgid-Bugs-To header.
+func (h Header) ReportMsgidBugsTo() string {
+	value, _ := h.Get(HeaderReportMsgidBugsTo)
+	return value
+}
+
+// LastTranslator returns the value of the Last-Translator header.
+func (h Header) LastTranslator() string {
+	value, _ := h.Get(HeaderLastTranslator)
+	return value
+}
+
+// LanguageTeam returns the value of the Language-Team header.
+func (h Header) LanguageTeam() string {
+	value, _ := h.Get(HeaderLanguageTeam)
+	return value
+}
+
+// Language returns the value of the Language header, e.g. "pt_BR".
+func (h Header) Language() string {
+	value, _ := h.Get(HeaderLanguage)
+	return value
+}
+
+// Generator returns the value of the X-Generator header.
+func (h Header) Generator() string {
+	value, _ := h.Get(HeaderGenerator)
+	return value
+}
+
+// POTCreationDate parses the value of the POT-Creation-Date header.
+//
+// An error is returned if the header is missing or is not a valid date.
+func (h Header) POTCreationDate() (time.Time, error) {
+	return h.date(HeaderPOTCreationDate)
+}
+
+// PORevisionDate parses the value of the PO-Revision-Date header.
+//
+// An error is returned if the header is missing or is not a valid date. The
+// "YEAR-MO-DA HO:MI+ZONE" placeholder of untranslated templates is not a
+// valid date.
+func (h Header) PORevisionDate() (time.Time, error) {
+	return h.date(HeaderPORevisionDate)
+}
+
+func (h Header) date(key string) (time.Time, error) {
+	value, ok := h.Get(key)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s: %w", key, ErrHeaderFieldNotFound)
+	}
+
+	value = strings.TrimSpace(value)
+	for _, layout := range headerDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid date %q", key, value)
+}
+
+// PluralForms parses the value of the Plural-Forms header as ParsePluralForms
+// does.
+//
+// An error is returned if the header is missing or malformed.
+func (h Header) PluralForms() (PluralForms, error) {
+	value, ok := h.Get(HeaderPluralForms)
+	if !ok {
+		return PluralForms{}, fmt.Errorf("%s: %w", HeaderPluralForms, ErrHeaderFieldNotFound)
+	}
+	return ParsePluralForms(value)
+}
+
+// ParsePluralForms parses a Plural-Forms value, which consists of key=value
+// pairs separated by semicolons such as "nplurals=2; plural=n != 1;". The
+// final semicolon is optional and any whitespace, including newlines, may
+// surround the keys and values. Keys other than nplurals and plural are
+// ignored. The expression itself is not validated.
+//
+// An error that describes the problem is returned if nplurals or plural is
+// missing, repeated, or invalid, or if a pair is not of the form key=value.
+// The error wraps ErrPluralFormsPlaceholder if value is the placeholder of a
+// .pot file.
+func ParsePluralForms(value string) (PluralForms, error) {
+	if strings.TrimSuffix(strings.Join(strings.Fields(value), ""), ";") == "nplurals=INTEGER;plural=EXPRESSION" {
+		return PluralForms{}, fmt.Errorf("%s: %w in %q", HeaderPluralForms, ErrPluralFormsPlaceholder, value)
+	}
+
+	pluralForms := PluralForms{}
+	seen := map[string]bool{}
+
+	pairs := strings.Split(value, ";")
+	for idx, pair := range pairs {
+		if len(strings.TrimSpace(pair)) == 0 {
+			if idx == len(pairs)-1 {
+				break
+			}
+			return PluralForms{}, pluralFormsError(value, "found an empty pair")
+		}
+
+		eq := strings.IndexByte(pair, '=')
+		if eq < 0 || !isPluralFormsKey(strings.TrimSpace(pair[:eq])) {
+			return PluralForms{}, pluralFormsError(value, "found %q, expected key=value", strings.TrimSpace(pair))
+		}
+
+		key := strings.TrimSpace(pair[:eq])
+		val := strings.TrimSpace(pair[eq+1:])
+		if seen[key] {
+			return PluralForms{}, pluralFormsError(value, "found duplicate key %q", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "nplurals":
+			nplurals, err := strconv.Atoi(val)
+			if err != nil || nplurals < 1 {
+				return PluralForms{}, pluralFormsError(value, "invalid nplurals %q", val)
+			}
+			pluralForms.NPlurals = nplurals
+		case "plural":
+			if len(val) == 0 {
+				return PluralForms{}, pluralFormsError(value, "empty plural")
+			}
+			pluralForms.Plural = val
+		}
+	}
+
+	for _, key := range []string{"nplurals", "plural"} {
+		if !seen[key] {
+			return PluralForms{}, pluralFormsError(value, "missing %s", key)
+		}
+	}
+
+	return pluralForms, nil
+}
+
+// isPluralFormsKey reports whether key consists of ASCII letters, digits,
+// underscores, and hyphens, and starts with a letter.
+func isPluralFormsKey(key string) bool {
+	for idx, c := range key {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case idx > 0 && ('0' <= c && c <= '9' || c == '_' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return len(key) > 0
+}
+
+// pluralFormsError returns an error that describes a problem with the
+// Plural-Forms value.
+func pluralFormsError(value string, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s in %q", HeaderPluralForms, fmt.Sprintf(format, args...), value)
+}
+
+// Extensions returns the "X-" header fields, such as X-Generator or
+// X-Poedit-Basepath, in the order in which they appear.
+func (h Header) Extensions() []HeaderField {
+	fields := []HeaderField{}
+	for _, field := range h.Fields {
+		if strings.HasPrefix(field.Key, "X-") {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// addFields parses the "Key: Value" lines of s and appends them to the fields
+// of the header. If a key is already present, the byte offset of the key
+// within s is returned along with false, and the fields that follow it are not
+// added.
+func (h *Header) addFields(s string) (int, bool) {
+	for _, loc := range regexHeaderKeyValue.FindAllStringSubmatchIndex(s, -1) {
+		key := s[loc[2]:loc[3]]
+		if _, ok := h.Get(key); ok {
+			return loc[2], false
+		}
+		h.Fields = append(h.Fields, HeaderField{Key: key, Value: s[loc[4]:loc[5]]})
+	}
+	return 0, true
+}
+
+// Clone returns a deep copy of the header.
+func (h Header) Clone() Header {
+	h.Message = h.Message.Clone()
+	h.Fields = append([]HeaderField(nil), h.Fields...)
+	return h
+}
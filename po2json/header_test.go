@@ -0,0 +1,156 @@
+package po2json
+
+import (
+	"errors"
+	"time"
+)
+
+const headerPO = `# Russian translations for the example package.
+msgid ""
+msgstr ""
+"Project-Id-Version: example 1.0\n"
+"Report-Msgid-Bugs-To: bugs@example.com\n"
+"POT-Creation-Date: 2021-03-04 10:15+0000\n"
+"PO-Revision-Date: 2021-03-05 14:22+0300\n"
+"Last-Translator: Ivan Petrov <ivan@example.com>\n"
+"Language-Team: Russian <ru@example.com>\n"
+"Language: ru\n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+"X-Generator: Poedit 2.4.2\n"
+"X-Poedit-Basepath: ..\n"
+`
+
+func (t *TestSuite) TestHeader_Accessors() {
+	catalog, err := ParseString(headerPO)
+	t.NoError(err)
+	header := catalog.Header
+
+	t.Equal("example 1.0", header.ProjectIDVersion())
+	t.Equal("bugs@example.com", header.ReportMsgidBugsTo())
+	t.Equal("Ivan Petrov <ivan@example.com>", header.LastTranslator())
+	t.Equal("Russian <ru@example.com>", header.LanguageTeam())
+	t.Equal("ru", header.Language())
+	t.Equal("Poedit 2.4.2", header.Generator())
+	t.Equal([]string{"Russian translations for the example package."}, header.TranslatorComments)
+
+	potCreationDate, err := header.POTCreationDate()
+	t.NoError(err)
+	t.True(time.Date(2021, 3, 4, 10, 15, 0, 0, time.UTC).Equal(potCreationDate))
+
+	poRevisionDate, err := header.PORevisionDate()
+	t.NoError(err)
+	t.True(time.Date(2021, 3, 5, 11, 22, 0, 0, time.UTC).Equal(poRevisionDate))
+	_, offset := poRevisionDate.Zone()
+	t.Equal(3*60*60, offset)
+
+	pluralForms, err := header.PluralForms()
+	t.NoError(err)
+	t.Equal(PluralForms{
+		NPlurals: 3,
+		Plural:   "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)",
+	}, pluralForms)
+
+	t.Equal([]HeaderField{
+		{Key: "X-Generator", Value: "Poedit 2.4.2"},
+		{Key: "X-Poedit-Basepath", Value: ".."},
+	}, header.Extensions())
+
+	basepath, ok := header.Get("X-Poedit-Basepath")
+	t.True(ok)
+	t.Equal("..", basepath)
+
+	keys := []string{}
+	for _, field := range header.Fields {
+		keys = append(keys, field.Key)
+	}
+	t.Equal([]string{
+		HeaderProjectIDVersion,
+		HeaderReportMsgidBugsTo,
+		HeaderPOTCreationDate,
+		HeaderPORevisionDate,
+		HeaderLastTranslator,
+		HeaderLanguageTeam,
+		HeaderLanguage,
+		HeaderMIMEVersion,
+		HeaderContentType,
+		HeaderContentTransferEncoding,
+		HeaderPluralForms,
+		HeaderGenerator,
+		"X-Poedit-Basepath",
+	}, keys)
+}
+
+func (t *TestSuite) TestHeader_Missing() {
+	header := Header{}
+	t.Empty(header.Language())
+	t.Empty(header.Extensions())
+
+	_, err := header.PORevisionDate()
+	t.True(errors.Is(err, ErrHeaderFieldNotFound))
+	t.EqualError(err, "PO-Revision-Date: header field not found")
+
+	_, err = header.PluralForms()
+	t.True(errors.Is(err, ErrHeaderFieldNotFound))
+}
+
+func (t *TestSuite) TestHeader_InvalidDate() {
+	header := Header{Fields: []HeaderField{{Key: HeaderPORevisionDate, Value: "YEAR-MO-DA HO:MI+ZONE"}}}
+	_, err := header.PORevisionDate()
+	t.EqualError(err, `PO-Revision-Date: invalid date "YEAR-MO-DA HO:MI+ZONE"`)
+}
+
+func (t *TestSuite) TestHeader_DateLayouts() {
+	for _, value := range []string{
+		"2021-03-05 14:22+0300",
+		"2021-03-05 14:22:00+0300",
+		"2021-03-05 14:22 +0300",
+		"2021-03-05T14:22:00+03:00",
+	} {
+		header := Header{Fields: []HeaderField{{Key: HeaderPORevisionDate, Value: value}}}
+		date, err := header.PORevisionDate()
+		t.NoError(err, value)
+		t.True(time.Date(2021, 3, 5, 11, 22, 0, 0, time.UTC).Equal(date), value)
+	}
+}
+
+func (t *TestSuite) TestHeader_PluralForms() {
+	for value, expected := range map[string]PluralForms{
+		"nplurals=1; plural=0;":                              {NPlurals: 1, Plural: "0"},
+		"nplurals=2; plural=(n != 1);":                       {NPlurals: 2, Plural: "(n != 1)"},
+		"  nplurals = 2 ;  plural = n != 1  ":                {NPlurals: 2, Plural: "n != 1"},
+		"nplurals=2; plural=n != 1":                          {NPlurals: 2, Plural: "n != 1"},
+		"plural=n==1 ? 0 : 1;\tnplurals=2;":                  {NPlurals: 2, Plural: "n==1 ? 0 : 1"},
+		"nplurals=3;\n plural=n%10==1 ? 0 :\n\tn ? 1 : 2;\n": {NPlurals: 3, Plural: "n%10==1 ? 0 :\n\tn ? 1 : 2"},
+		"nplurals=2; plural=n>1; x-comment=French":           {NPlurals: 2, Plural: "n>1"},
+	} {
+		header := Header{Fields: []HeaderField{{Key: HeaderPluralForms, Value: value}}}
+		pluralForms, err := header.PluralForms()
+		t.NoError(err, value)
+		t.Equal(expected, pluralForms, value)
+	}
+
+	for value, expected := range map[string]string{
+		"":                                     `Plural-Forms: missing nplurals in ""`,
+		"plural=n != 1;":                       `Plural-Forms: missing nplurals in "plural=n != 1;"`,
+		"nplurals=2":                           `Plural-Forms: missing plural in "nplurals=2"`,
+		"nplurals=0; plural=0;":                `Plural-Forms: invalid nplurals "0" in "nplurals=0; plural=0;"`,
+		"nplurals=INTEGER; plural=EXPRESSION;": `Plural-Forms: template placeholder in "nplurals=INTEGER; plural=EXPRESSION;"`,
+		"nplurals=INTEGER; plural=n != 1;":     `Plural-Forms: invalid nplurals "INTEGER" in "nplurals=INTEGER; plural=n != 1;"`,
+		"nplurals=2; plural=;":                 `Plural-Forms: empty plural in "nplurals=2; plural=;"`,
+		"nplurals=2;; plural=n != 1":           `Plural-Forms: found an empty pair in "nplurals=2;; plural=n != 1"`,
+		"nplurals=2; n != 1":                   `Plural-Forms: found "n != 1", expected key=value in "nplurals=2; n != 1"`,
+		"nplurals=2; plural=n; plural=n != 1;": `Plural-Forms: found duplicate key "plural" in "nplurals=2; plural=n; plural=n != 1;"`,
+	} {
+		header := Header{Fields: []HeaderField{{Key: HeaderPluralForms, Value: value}}}
+		_, err := header.PluralForms()
+		t.EqualError(err, expected, value)
+	}
+
+	for _, value := range []string{"nplurals=INTEGER; plural=EXPRESSION;", "nplurals=INTEGER; plural=EXPRESSION", " nplurals = INTEGER;\n plural = EXPRESSION; "} {
+		_, err := ParsePluralForms(value)
+		t.ErrorIs(err, ErrPluralFormsPlaceholder, value)
+	}
+}
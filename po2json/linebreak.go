@@ -0,0 +1,240 @@
+package po2json
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// breakClass is a line breaking class of the Unicode line breaking algorithm
+// (UAX #14). Only the classes that affect .po files are distinguished.
+type breakClass int
+
+const (
+	classAL breakClass = iota // Alphabetic
+	classBA                   // Break after
+	classB2                   // Break opportunity before and after
+	classBB                   // Break before
+	classCL                   // Close punctuation
+	classCM                   // Combining mark
+	classCP                   // Close parenthesis
+	classEX                   // Exclamation or interrogation
+	classGL                   // Non-breaking glue
+	classHY                   // Hyphen
+	classID                   // Ideographic
+	classIN                   // Inseparable
+	classIS                   // Infix numeric separator
+	classNS                   // Nonstarter
+	classNU                   // Numeric
+	classOP                   // Open punctuation
+	classPO                   // Postfix numeric
+	classPR                   // Prefix numeric
+	classQU                   // Quotation
+	classSP                   // Space
+	classSY                   // Symbols allowing break after
+)
+
+var asciiBreakClasses = [128]breakClass{
+	'\t': classBA,
+	' ':  classSP,
+	'!':  classEX,
+	'"':  classQU,
+	'$':  classPR,
+	'%':  classPO,
+	'\'': classQU,
+	'(':  classOP,
+	')':  classCP,
+	'+':  classPR,
+	',':  classIS,
+	'-':  classHY,
+	'.':  classIS,
+	'/':  classSY,
+	'0':  classNU,
+	'1':  classNU,
+	'2':  classNU,
+	'3':  classNU,
+	'4':  classNU,
+	'5':  classNU,
+	'6':  classNU,
+	'7':  classNU,
+	'8':  classNU,
+	'9':  classNU,
+	':':  classIS,
+	';':  classIS,
+	'?':  classEX,
+	'[':  classOP,
+	'\\': classPR,
+	']':  classCP,
+	'{':  classOP,
+	'|':  classBA,
+	'}':  classCL,
+}
+
+var otherBreakClasses = map[rune]breakClass{
+	' ': classGL, // no-break space
+	'«': classQU, // «
+	'°': classPO, // °
+	'»': classQU, // »
+	'–': classBA, // en dash
+	'—': classB2, // em dash
+	'‘': classQU, // ‘
+	'’': classQU, // ’
+	'“': classQU, // “
+	'”': classQU, // ”
+	'…': classIN, // …
+	'‰': classPO, // ‰
+	'€': classPR, // €
+	'£': classPR, // £
+	'、': classCL, // 、
+	'。': classCL, // 。
+	'「': classOP, // 「
+	'」': classCL, // 」
+	'（': classOP, // （
+	'）': classCL, // ）
+	'，': classCL, // ，
+}
+
+// lineBreakClass returns the line breaking class of r.
+func lineBreakClass(r rune) breakClass {
+	if r < utf8.RuneSelf {
+		return asciiBreakClasses[r]
+	}
+	if class, ok := otherBreakClasses[r]; ok {
+		return class
+	}
+
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return classCM
+	case unicode.Is(unicode.Nd, r):
+		return classNU
+	case isWide(r):
+		return classID
+	}
+	return classAL
+}
+
+// isWide reports whether r occupies two columns on a terminal.
+func isWide(r rune) bool {
+	return 0x1100 <= r && r <= 0x115f ||
+		0x2e80 <= r && r <= 0x303e ||
+		0x3041 <= r && r <= 0x33ff ||
+		0x3400 <= r && r <= 0x4dbf ||
+		0x4e00 <= r && r <= 0x9fff ||
+		0xa000 <= r && r <= 0xa4cf ||
+		0xac00 <= r && r <= 0xd7a3 ||
+		0xf900 <= r && r <= 0xfaff ||
+		0xfe30 <= r && r <= 0xfe4f ||
+		0xff00 <= r && r <= 0xff60 ||
+		0xffe0 <= r && r <= 0xffe6 ||
+		0x20000 <= r && r <= 0x3fffd
+}
+
+// runeWidth returns the number of columns that r occupies on a terminal.
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// breakOpportunities returns, for every byte of s, whether the line may be
+// broken before it according to the rules of UAX #14 as implemented by GNU
+// libunistring, which is what GNU gettext uses to wrap strings.
+func breakOpportunities(s string) []bool {
+	breaks := make([]bool, len(s))
+
+	// base is the class of the last character that is not a space or a
+	// combining mark and spaces reports whether spaces follow it.
+	base := classSP
+	spaces := false
+	for idx, r := range s {
+		class := lineBreakClass(r)
+		if idx == 0 {
+			base = class
+			if class == classCM {
+				base = classAL
+			}
+			continue
+		}
+
+		if class == classSP {
+			spaces = true
+			continue
+		}
+
+		if class == classCM && !spaces {
+			continue
+		}
+		if class == classCM {
+			class = classAL
+		}
+
+		breaks[idx] = canBreak(base, class, spaces)
+		base = class
+		spaces = false
+	}
+
+	return breaks
+}
+
+// canBreak reports whether the line may be broken between a character of
+// class before and a character of class after, with spaces in between if
+// spaces is true.
+func canBreak(before breakClass, after breakClass, spaces bool) bool {
+	switch {
+	// LB12 and LB12a: Do not break around non-breaking glue.
+	case before == classGL && !spaces:
+		return false
+	case after == classGL && !spaces && before != classBA && before != classHY:
+		return false
+	// LB13: Do not break before closing punctuation, even after spaces.
+	case after == classCL || after == classCP || after == classEX || after == classIS || after == classSY:
+		return false
+	// LB14: Do not break after opening punctuation, even after spaces.
+	case before == classOP:
+		return false
+	// LB15, LB16, and LB17.
+	case before == classQU && after == classOP:
+		return false
+	case (before == classCL || before == classCP) && after == classNS:
+		return false
+	case before == classB2 && after == classB2:
+		return false
+	// LB18: Break after spaces.
+	case spaces:
+		return true
+	// LB19: Do not break around quotation marks.
+	case before == classQU || after == classQU:
+		return false
+	// LB21: Do not break before hyphens and other small characters.
+	case after == classBA || after == classHY || after == classNS || before == classBB:
+		return false
+	// LB22: Do not break before ellipses.
+	case after == classIN && (before == classAL || before == classID || before == classIN || before == classNU):
+		return false
+	// LB23 and LB24: Do not break between letters, numbers, and affixes.
+	case before == classID && after == classPO,
+		before == classAL && after == classNU,
+		before == classNU && after == classAL,
+		before == classPR && (after == classID || after == classAL),
+		before == classPO && after == classAL:
+		return false
+	// LB25: Do not break within numbers.
+	case (before == classCL || before == classCP || before == classNU) && (after == classPO || after == classPR),
+		(before == classPO || before == classPR) && (after == classOP || after == classNU),
+		(before == classHY || before == classIS || before == classNU || before == classSY) && after == classNU:
+		return false
+	// LB28, LB29, and LB30: Do not break within words.
+	case before == classAL && after == classAL,
+		before == classIS && after == classAL,
+		(before == classAL || before == classNU) && after == classOP,
+		before == classCP && (after == classAL || after == classNU):
+		return false
+	}
+
+	// LB31: Break everywhere else.
+	return true
+}
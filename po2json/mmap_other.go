@@ -0,0 +1,20 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package po2json
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the contents of file into memory on platforms that don't
+// support mmap. The returned function does nothing.
+func mapFile(file *os.File) ([]byte, func() error, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
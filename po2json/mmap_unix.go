@@ -0,0 +1,34 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package po2json
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mapFile maps the contents of file into memory. The returned function unmaps
+// them.
+func mapFile(file *os.File) ([]byte, func() error, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		return []byte{}, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("%s: file of %d bytes is too large to map", file.Name(), size)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: file.Name(), Err: err}
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,388 @@
+package po2json
+
+// MO file format documentation: https://www.gnu.org/software/gettext/manual/html_node/MO-Files.html
+import (
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"golang.org/x/text/encoding"
+)
+
+const (
+	// moMagic is the magic number at the start of a .mo file, in the byte
+	// order of the file.
+	moMagic = 0x950412de
+	// moHeaderSize is the size of the header of a .mo file without system
+	// dependent strings.
+	moHeaderSize = 28
+	// moSysdepHeaderSize is the size of the header of a .mo file with system
+	// dependent strings.
+	moSysdepHeaderSize = 48
+	// moSegmentsEnd marks the last segment of a system dependent string.
+	moSegmentsEnd = 0xffffffff
+
+	// contextSeparator separates the msgctxt from the msgid in a .mo file.
+	contextSeparator = "\x04"
+	// pluralSeparator separates the msgid from the msgid_plural, and the
+	// plural translations from each other, in a .mo file.
+	pluralSeparator = "\x00"
+)
+
+// ParseMOFile reads the contents of a compiled .mo file and parses it into a
+// Catalog.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format. Errors in the format are
+// reported as a *MOError that contains the file path.
+func ParseMOFile(filePath string) (*Catalog, error) {
+	fileContents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMO(filePath, fileContents)
+}
+
+// ParseMOFS reads the contents of the compiled .mo file at filePath within
+// fsys and parses it into a Catalog.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format. Errors in the format are
+// reported as a *MOError that contains the file path.
+func ParseMOFS(fsys fs.FS, filePath string) (*Catalog, error) {
+	fileContents, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMO(filePath, fileContents)
+}
+
+// ParseMOBytes parses a byte slice representation of a compiled .mo file into
+// a Catalog.
+//
+// An error is returned if the file is in an invalid format. Errors in the
+// format are reported as a *MOError.
+func ParseMOBytes(fileContents []byte) (*Catalog, error) {
+	return parseMO("", fileContents)
+}
+
+// ParseMOReader reads a compiled .mo file from r and parses it into a Catalog.
+// Unlike a .po file, a .mo file is read into memory in its entirety.
+//
+// An error is returned if reading from r fails or if the file is in an
+// invalid format. Errors in the format are reported as a *MOError.
+func ParseMOReader(r io.Reader) (*Catalog, error) {
+	fileContents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMO("", fileContents)
+}
+
+// moReader reads the tables of a .mo file.
+type moReader struct {
+	file     string
+	data     []byte
+	order    binary.ByteOrder
+	revision uint32
+	nstrings int
+	// origTabOffset and transTabOffset are the offsets of the tables that
+	// describe the original and translated strings.
+	origTabOffset  int
+	transTabOffset int
+	// hashTabSize and hashTabOffset describe the hash table. hashTabSize is
+	// zero if the file doesn't have one.
+	hashTabSize   int
+	hashTabOffset int
+}
+
+// newMOReader checks the header of the .mo file in data and returns a reader
+// for its tables.
+//
+// Either byte order is accepted, as are major revisions 0 and 1.
+func newMOReader(file string, data []byte) (*moReader, error) {
+	mr := &moReader{file: file, data: data}
+	if len(data) < moHeaderSize {
+		return nil, mr.errorf(0, "Invalid .mo file. Found %d bytes, expected at least %d.", len(data), moHeaderSize)
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(data) == moMagic:
+		mr.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data) == moMagic:
+		mr.order = binary.BigEndian
+	default:
+		return nil, mr.errorf(0, "Invalid .mo file. Found magic number 0x%08x, expected 0x%08x.", binary.LittleEndian.Uint32(data), uint32(moMagic))
+	}
+
+	mr.revision = mr.order.Uint32(data[4:])
+	if major := mr.revision >> 16; major > 1 {
+		return nil, mr.errorf(4, "Invalid .mo file. Found unsupported revision %d.%d.", major, mr.revision&0xffff)
+	}
+	if mr.revision&0xffff >= 1 && len(data) < moSysdepHeaderSize {
+		return nil, mr.errorf(0, "Invalid .mo file. Found %d bytes, expected at least %d.", len(data), moSysdepHeaderSize)
+	}
+
+	mr.nstrings = int(mr.order.Uint32(data[8:]))
+	mr.origTabOffset = int(mr.order.Uint32(data[12:]))
+	mr.transTabOffset = int(mr.order.Uint32(data[16:]))
+	mr.hashTabSize = int(mr.order.Uint32(data[20:]))
+	mr.hashTabOffset = int(mr.order.Uint32(data[24:]))
+	return mr, nil
+}
+
+// parseMO parses the contents of a .mo file into a Catalog.
+//
+// System dependent strings, which minor revision 1 adds, are expanded into the
+// "<PRId64>" notation of the .po file that they were compiled from. The
+// strings are transcoded to UTF-8 according to the charset in the
+// Content-Type header.
+func parseMO(file string, data []byte) (*Catalog, error) {
+	mr, err := newMOReader(file, data)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := [][2]string{}
+	for idx := 0; idx < mr.nstrings; idx++ {
+		original, err := mr.string(mr.origTabOffset, idx)
+		if err != nil {
+			return nil, err
+		}
+		translation, err := mr.string(mr.transTabOffset, idx)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]string{original, translation})
+	}
+
+	sysdepPairs, err := mr.sysdepPairs()
+	if err != nil {
+		return nil, err
+	}
+	pairs = append(pairs, sysdepPairs...)
+
+	var decoder *encoding.Decoder
+	for _, pair := range pairs {
+		if len(pair[0]) > 0 {
+			continue
+		}
+		enc, err := mr.charset(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		if enc != nil {
+			decoder = enc.NewDecoder()
+		}
+	}
+
+	catalog := NewCatalog()
+	for _, pair := range pairs {
+		if decoder != nil {
+			var err error
+			if pair[0], err = decoder.String(pair[0]); err != nil {
+				return nil, err
+			}
+			if pair[1], err = decoder.String(pair[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		msg := newMOMessage(pair[0], pair[1])
+		if len(msg.Msgctxt) == 0 && len(msg.Msgid) == 0 {
+			if catalog.Header, err = mr.header(msg); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		catalog.Add(msg)
+	}
+
+	return catalog, nil
+}
+
+// newMOMessage splits the original and translated strings of a .mo file into
+// a Message.
+func newMOMessage(original string, translation string) *Message {
+	msg := &Message{Msgid: original}
+	if idx := strings.Index(msg.Msgid, contextSeparator); idx >= 0 {
+		msg.Msgctxt, msg.Msgid = msg.Msgid[:idx], msg.Msgid[idx+len(contextSeparator):]
+	}
+	if idx := strings.Index(msg.Msgid, pluralSeparator); idx >= 0 {
+		msg.Msgid, msg.MsgidPlural = msg.Msgid[:idx], msg.Msgid[idx+len(pluralSeparator):]
+		msg.Plurals = strings.Split(translation, pluralSeparator)
+	} else {
+		msg.Translation = translation
+	}
+	return msg
+}
+
+// header returns the header whose message is msg.
+func (mr *moReader) header(msg *Message) (Header, error) {
+	header := Header{Message: *msg}
+	if offset, ok := header.addFields(msg.Translation); !ok {
+		key := regexHeaderKeyValue.FindStringSubmatch(msg.Translation[offset:])[1]
+		return Header{}, mr.errorf(0, `Invalid .mo file. Found duplicate header key "%s".`, key)
+	}
+	return header, nil
+}
+
+// charset returns the encoding named by the Content-Type of the header
+// translation. A nil encoding is returned for UTF-8 and files without a
+// charset.
+func (mr *moReader) charset(translation string) (encoding.Encoding, error) {
+	matches := regexCharset.FindStringSubmatch(translation)
+	if matches == nil {
+		return nil, nil
+	}
+
+	enc, err := lookupCharset(matches[1])
+	if err != nil {
+		moErr := mr.errorf(0, "Invalid .mo file. Found unsupported charset %q", matches[1])
+		moErr.Err = err
+		return nil, moErr
+	}
+	return enc, nil
+}
+
+// sysdepPairs returns the original and translated system dependent strings of
+// the file, or nothing if the minor revision is 0.
+func (mr *moReader) sysdepPairs() ([][2]string, error) {
+	if mr.revision&0xffff == 0 {
+		return nil, nil
+	}
+
+	segments, err := mr.sysdepSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	nSysdepStrings := int(mr.order.Uint32(mr.data[36:]))
+	origSysdepTabOffset := int(mr.order.Uint32(mr.data[40:]))
+	transSysdepTabOffset := int(mr.order.Uint32(mr.data[44:]))
+
+	pairs := [][2]string{}
+	for idx := 0; idx < nSysdepStrings; idx++ {
+		original, err := mr.sysdepString(origSysdepTabOffset, idx, segments)
+		if err != nil {
+			return nil, err
+		}
+		translation, err := mr.sysdepString(transSysdepTabOffset, idx, segments)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]string{original, translation})
+	}
+	return pairs, nil
+}
+
+// uint32 returns the 32-bit integer at offset, or false if the file is too
+// short to contain it.
+func (mr *moReader) uint32(offset int) (int, bool) {
+	if offset < 0 || offset > len(mr.data)-4 {
+		return 0, false
+	}
+	return int(mr.order.Uint32(mr.data[offset:])), true
+}
+
+// string returns the string described by the idx-th length and offset pair of
+// the table at tableOffset.
+func (mr *moReader) string(tableOffset int, idx int) (string, error) {
+	b, err := mr.bytes(tableOffset, idx)
+	return string(b), err
+}
+
+// bytes returns the string described by the idx-th length and offset pair of
+// the table at tableOffset without copying it.
+func (mr *moReader) bytes(tableOffset int, idx int) ([]byte, error) {
+	descOffset := tableOffset + 8*idx
+	length, ok1 := mr.uint32(descOffset)
+	offset, ok2 := mr.uint32(descOffset + 4)
+	if !ok1 || !ok2 {
+		return nil, mr.errorf(descOffset, "Invalid .mo file. Found string table entry %d beyond the end of the file.", idx)
+	}
+	if offset > len(mr.data) || length < 0 || length > len(mr.data)-offset {
+		return nil, mr.errorf(descOffset, "Invalid .mo file. Found string %d beyond the end of the file.", idx)
+	}
+	return mr.data[offset : offset+length], nil
+}
+
+// sysdepSegments returns the names of the system dependent segments, such as
+// "PRId64", expanded into the text that they stand for in a .po file.
+func (mr *moReader) sysdepSegments() ([]string, error) {
+	nSegments := int(mr.order.Uint32(mr.data[28:]))
+	segmentsOffset := int(mr.order.Uint32(mr.data[32:]))
+
+	segments := []string{}
+	for idx := 0; idx < nSegments; idx++ {
+		name, err := mr.string(segmentsOffset, idx)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimRight(name, "\x00")
+
+		// The "I" flag of glibc is written as is. Everything else is the
+		// name of an <inttypes.h> macro, which is written in angle brackets.
+		if name != "I" {
+			name = "<" + name + ">"
+		}
+		segments = append(segments, name)
+	}
+
+	return segments, nil
+}
+
+// sysdepString returns the idx-th system dependent string of the table at
+// tableOffset with its system dependent segments expanded.
+func (mr *moReader) sysdepString(tableOffset int, idx int, segments []string) (string, error) {
+	descOffset, ok := mr.uint32(tableOffset + 4*idx)
+	if !ok {
+		return "", mr.errorf(tableOffset+4*idx, "Invalid .mo file. Found system dependent string table entry %d beyond the end of the file.", idx)
+	}
+	offset, ok := mr.uint32(descOffset)
+	if !ok {
+		return "", mr.errorf(descOffset, "Invalid .mo file. Found system dependent string %d beyond the end of the file.", idx)
+	}
+
+	ss := strings.Builder{}
+	for pairOffset := descOffset + 4; ; pairOffset += 8 {
+		segsize, ok1 := mr.uint32(pairOffset)
+		sysdepref, ok2 := mr.uint32(pairOffset + 4)
+		if !ok1 || !ok2 || offset > len(mr.data) || segsize < 0 || segsize > len(mr.data)-offset {
+			return "", mr.errorf(pairOffset, "Invalid .mo file. Found system dependent string %d beyond the end of the file.", idx)
+		}
+
+		ss.Write(mr.data[offset : offset+segsize])
+		offset += segsize
+		if uint32(sysdepref) == moSegmentsEnd {
+			break
+		}
+		if sysdepref < 0 || sysdepref >= len(segments) {
+			return "", mr.errorf(pairOffset+4, "Invalid .mo file. Found system dependent segment %d, expected fewer than %d.", sysdepref, len(segments))
+		}
+		ss.WriteString(segments[sysdepref])
+	}
+
+	// The static segments include the NUL byte that terminates the string.
+	return strings.TrimSuffix(ss.String(), "\x00"), nil
+}
+
+// hashString returns the hashpjw hash of s up to its first NUL byte, which is
+// the hash function of the hash table of a .mo file.
+func hashString(s string) uint32 {
+	var hash uint32
+	for idx := 0; idx < len(s) && s[idx] != 0; idx++ {
+		hash = hash<<4 + uint32(s[idx])
+		if g := hash & 0xf0000000; g != 0 {
+			hash ^= g >> 24
+			hash ^= g
+		}
+	}
+	return hash
+}
@@ -0,0 +1,216 @@
+package po2json
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing/iotest"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	moFilePath   = "../testdata/canonical.mo"
+	moBEFilePath = "../testdata/canonical_be.mo"
+	// msgfmtFilePath is a .mo file that GNU msgfmt compiled.
+	msgfmtFilePath = "../testdata/linux-pam-eo.mo"
+)
+
+// buildMO returns a .mo file without a hash table that contains the pairs of
+// original and translated strings in the specified order.
+func buildMO(order binary.ByteOrder, revision uint32, pairs ...[2]string) []byte {
+	words := []uint32{moMagic, revision, uint32(len(pairs)), moHeaderSize, moHeaderSize + 8*uint32(len(pairs)), 0, 0}
+	strs := []byte{}
+	offset := moHeaderSize + 16*uint32(len(pairs))
+	for column := 0; column < 2; column++ {
+		for _, pair := range pairs {
+			words = append(words, uint32(len(pair[column])), offset+uint32(len(strs)))
+			strs = append(append(strs, pair[column]...), 0)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	binary.Write(&buf, order, words)
+	buf.Write(strs)
+	return buf.Bytes()
+}
+
+func (t *TestSuite) TestParseMOFile_Valid() {
+	catalog, err := ParseMOFile(moFilePath)
+	t.Require().NoError(err)
+
+	poCatalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	t.Equal(poCatalog.Header.Fields, catalog.Header.Fields)
+	t.Equal(poCatalog.Header.Translation, catalog.Header.Translation)
+	t.Len(catalog.Messages, 4)
+	t.Empty(catalog.Obsolete)
+
+	for _, poMsg := range poCatalog.Messages {
+		msg, ok := catalog.Lookup(poMsg.Msgctxt, poMsg.Msgid)
+		if poMsg.HasFlag("fuzzy") {
+			t.False(ok, poMsg.Msgid)
+			continue
+		}
+
+		t.Require().True(ok, poMsg.Msgid)
+		t.Equal(poMsg.MsgidPlural, msg.MsgidPlural)
+		t.Equal(poMsg.Translation, msg.Translation)
+		t.Equal(poMsg.Plurals, msg.Plurals)
+	}
+}
+
+func (t *TestSuite) TestParseMOFile_BigEndian() {
+	little, err := ParseMOFile(moFilePath)
+	t.Require().NoError(err)
+
+	big, err := ParseMOFile(moBEFilePath)
+	t.Require().NoError(err)
+
+	t.Equal(little.Map(), big.Map())
+}
+
+func (t *TestSuite) TestParseMOFile_FileNotFound() {
+	catalog, err := ParseMOFile("./this/doesnt/exist")
+	t.Nil(catalog)
+	t.True(os.IsNotExist(err))
+}
+
+func (t *TestSuite) TestParseMOFS_Valid() {
+	catalog, err := ParseMOFS(os.DirFS("../testdata"), "canonical.mo")
+	t.Require().NoError(err)
+	t.Equal("ru", catalog.Header.Language())
+}
+
+func (t *TestSuite) TestParseMOFS_ParseError() {
+	_, err := ParseMOFS(os.DirFS("../testdata"), "test.po")
+	t.EqualError(err, "test.po: offset 0: Invalid .mo file. Found magic number 0x6967736d, expected 0x950412de.")
+}
+
+func (t *TestSuite) TestParseMOReader_Valid() {
+	fileContents, err := ioutil.ReadFile(moFilePath)
+	t.Require().NoError(err)
+
+	catalog, err := ParseMOReader(iotest.OneByteReader(bytes.NewReader(fileContents)))
+	t.Require().NoError(err)
+	t.Len(catalog.Messages, 4)
+}
+
+func (t *TestSuite) TestParseMOReader_ReadError() {
+	_, err := ParseMOReader(iotest.ErrReader(errors.New("read failed")))
+	t.EqualError(err, "read failed")
+}
+
+func (t *TestSuite) TestParseMOBytes_ContextAndPlurals() {
+	catalog, err := ParseMOBytes(buildMO(binary.LittleEndian, 0,
+		[2]string{"", "Language: de\nPlural-Forms: nplurals=2; plural=(n != 1);\n"},
+		[2]string{"menu\x04File\x00Files", "Datei\x00Dateien"},
+		[2]string{"menu\x04Open", "Öffnen"},
+	))
+	t.Require().NoError(err)
+
+	t.Equal("de", catalog.Header.Language())
+	t.Equal(map[string]interface{}{
+		"": map[string]interface{}{
+			"": map[string]interface{}{
+				"Language":     "de",
+				"Plural-Forms": "nplurals=2; plural=(n != 1);",
+			},
+		},
+		"menu": map[string]interface{}{
+			"File": map[string]interface{}{
+				"msgidPlural": "Files",
+				"plurals":     []string{"Datei", "Dateien"},
+			},
+			"Open": map[string]interface{}{
+				"translation": "Öffnen",
+			},
+		},
+	}, catalog.Map())
+}
+
+func (t *TestSuite) TestParseMOBytes_Charset() {
+	translation, err := charmap.KOI8R.NewEncoder().String("Войти")
+	t.Require().NoError(err)
+
+	catalog, err := ParseMOBytes(buildMO(binary.BigEndian, 0,
+		[2]string{"", "Content-Type: text/plain; charset=KOI8-R\n"},
+		[2]string{"Log in", translation},
+	))
+	t.Require().NoError(err)
+
+	msg, ok := catalog.Lookup("", "Log in")
+	t.True(ok)
+	t.Equal("Войти", msg.Translation)
+}
+
+func (t *TestSuite) TestParseMOBytes_UnsupportedCharset() {
+	_, err := ParseMOBytes(buildMO(binary.LittleEndian, 0,
+		[2]string{"", "Content-Type: text/plain; charset=UTF-7\n"},
+	))
+	t.EqualError(err, `offset 0: Invalid .mo file. Found unsupported charset "UTF-7": charset is not supported`)
+	t.True(errors.Is(err, ErrUnsupportedCharset))
+}
+
+// sysdepMO returns a .mo file whose only message is the system dependent
+// string "%<PRIu64> files", translated as "%Id <PRIu64> Dateien".
+func sysdepMO() []byte {
+	words := []uint32{
+		// Header with no static strings.
+		moMagic, 1, 0, moSysdepHeaderSize, moSysdepHeaderSize, 0, 0,
+		// Two segments, described at offset 48.
+		2, 48,
+		// One system dependent string, whose original is described at offset
+		// 64 and whose translation is described at offset 68.
+		1, 64, 68,
+		// Segments "PRIu64" at offset 120 and "I" at offset 127.
+		6, 120, 1, 127,
+		// Offsets of the original and translated strings.
+		72, 92,
+		// "%" PRIu64 " files" at offset 129.
+		129, 1, 0, 7, moSegmentsEnd,
+		// "%" I "d " PRIu64 " Dateien" at offset 137.
+		137, 1, 1, 2, 0, 9, moSegmentsEnd,
+	}
+
+	buf := bytes.Buffer{}
+	binary.Write(&buf, binary.LittleEndian, words)
+	buf.WriteString("PRIu64\x00I\x00% files\x00%d  Dateien\x00")
+	return buf.Bytes()
+}
+
+func (t *TestSuite) TestParseMOBytes_SysdepStrings() {
+	catalog, err := ParseMOBytes(sysdepMO())
+	t.Require().NoError(err)
+
+	msg, ok := catalog.Lookup("", "%<PRIu64> files")
+	t.Require().True(ok)
+	t.Equal("%Id <PRIu64> Dateien", msg.Translation)
+}
+
+func (t *TestSuite) TestParseMOBytes_Invalid() {
+	for expected, fileContents := range map[string][]byte{
+		"offset 0: Invalid .mo file. Found 4 bytes, expected at least 28.":                    {0xde, 0x12, 0x04, 0x95},
+		"offset 0: Invalid .mo file. Found magic number 0x00000000, expected 0x950412de.":     make([]byte, moHeaderSize),
+		"offset 4: Invalid .mo file. Found unsupported revision 2.0.":                         buildMO(binary.LittleEndian, 2<<16),
+		"offset 28: Invalid .mo file. Found string table entry 0 beyond the end of the file.": buildMO(binary.LittleEndian, 0, [2]string{"Log in", "Войти"})[:moHeaderSize],
+		"offset 0: Invalid .mo file. Found 28 bytes, expected at least 48.":                   buildMO(binary.LittleEndian, 1),
+		`offset 0: Invalid .mo file. Found duplicate header key "Language".`: buildMO(binary.LittleEndian, 0,
+			[2]string{"", "Language: de\nLanguage: fr\n"},
+		),
+	} {
+		catalog, err := ParseMOBytes(fileContents)
+		t.Nil(catalog)
+		t.EqualError(err, expected)
+
+		var moErr *MOError
+		t.True(errors.As(err, &moErr), expected)
+	}
+
+	fileContents := buildMO(binary.BigEndian, 0, [2]string{"Log in", "Войти"})
+	_, err := ParseMOBytes(fileContents[:len(fileContents)-4])
+	t.EqualError(err, "offset 36: Invalid .mo file. Found string 0 beyond the end of the file.")
+}
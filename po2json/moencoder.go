@@ -0,0 +1,215 @@
+package po2json
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A MOEncoder writes catalogs to an output stream in the binary .mo format
+// that msgfmt produces.
+type MOEncoder struct {
+	w         io.Writer
+	order     binary.ByteOrder
+	useFuzzy  bool
+	hashTable bool
+}
+
+// NewMOEncoder returns a new MOEncoder that writes to w. By default, the file
+// is little-endian, contains a hash table, and omits fuzzy messages, as msgfmt
+// does.
+func NewMOEncoder(w io.Writer) *MOEncoder {
+	return &MOEncoder{w: w, order: binary.LittleEndian, hashTable: true}
+}
+
+// SetByteOrder sets the byte order of the file, as msgfmt --endianness does.
+func (e *MOEncoder) SetByteOrder(order binary.ByteOrder) {
+	e.order = order
+}
+
+// SetUseFuzzy sets whether messages that are marked as fuzzy are written, as
+// msgfmt --use-fuzzy does. The header is written even if it is fuzzy.
+func (e *MOEncoder) SetUseFuzzy(useFuzzy bool) {
+	e.useFuzzy = useFuzzy
+}
+
+// SetHashTable sets whether the hash table that speeds up lookups is written.
+// Disabling it has the same effect as msgfmt --no-hash.
+func (e *MOEncoder) SetHashTable(hashTable bool) {
+	e.hashTable = hashTable
+}
+
+// Encode writes c to the stream in the layout used by msgfmt: the original
+// strings sorted in byte order, the translations, the hash table, and finally
+// the strings themselves. Contexts and plurals are joined with the separators
+// of the .mo format. Obsolete and untranslated messages are omitted, and so
+// are fuzzy messages unless SetUseFuzzy is set. The strings are encoded in the
+// charset named by the Content-Type header.
+//
+// The output is byte for byte the same as that of msgfmt for the same input,
+// except that "<PRId64>" style system dependent strings are written as they
+// are rather than as the system dependent strings of revision 1.
+func (e *MOEncoder) Encode(c *Catalog) error {
+	pairs, err := e.pairs(c)
+	if err != nil {
+		return err
+	}
+
+	nstrings := uint32(len(pairs))
+	hashTabSize := uint32(0)
+	if e.hashTable {
+		hashTabSize = nextPrime(nstrings * 4 / 3)
+		if hashTabSize <= 2 {
+			hashTabSize = 3
+		}
+	}
+
+	origTabOffset := uint32(moHeaderSize)
+	transTabOffset := origTabOffset + 8*nstrings
+	hashTabOffset := transTabOffset + 8*nstrings
+	stringsOffset := hashTabOffset + 4*hashTabSize
+
+	words := []uint32{moMagic, 0, nstrings, origTabOffset, transTabOffset, hashTabSize, hashTabOffset}
+	offset := stringsOffset
+	for column := 0; column < 2; column++ {
+		for _, pair := range pairs {
+			words = append(words, uint32(len(pair[column])), offset)
+			offset += uint32(len(pair[column])) + 1
+		}
+	}
+
+	words = append(words, hashTable(pairs, hashTabSize)...)
+
+	bw := bufio.NewWriter(e.w)
+	if err := binary.Write(bw, e.order, words); err != nil {
+		return err
+	}
+	for column := 0; column < 2; column++ {
+		for _, pair := range pairs {
+			bw.WriteString(pair[column])
+			bw.WriteByte(0)
+		}
+	}
+	return bw.Flush()
+}
+
+// pairs returns the original and translated strings of the messages that are
+// written to the file, sorted by the original strings.
+func (e *MOEncoder) pairs(c *Catalog) ([][2]string, error) {
+	messages := []*Message{}
+	if header := c.Header.Message; len(header.Translation) > 0 {
+		messages = append(messages, &header)
+	}
+
+	// Later messages shadow earlier ones with the same msgctxt and msgid.
+	seen := map[[2]string]int{}
+	for _, msg := range c.Messages {
+		if !e.include(msg) {
+			continue
+		}
+		key := [2]string{msg.Msgctxt, msg.Msgid}
+		if idx, ok := seen[key]; ok {
+			messages[idx] = msg
+			continue
+		}
+		seen[key] = len(messages)
+		messages = append(messages, msg)
+	}
+
+	enc, err := headerEncoding(c.Header)
+	if err != nil {
+		return nil, err
+	}
+	encode := func(s string) (string, error) { return s, nil }
+	if enc != nil {
+		encode = enc.NewEncoder().String
+	}
+
+	pairs := [][2]string{}
+	for _, msg := range messages {
+		original := msg.Msgid
+		if len(msg.Msgctxt) > 0 {
+			original = msg.Msgctxt + contextSeparator + original
+		}
+		translation := msg.Translation
+		if len(msg.MsgidPlural) > 0 {
+			original += pluralSeparator + msg.MsgidPlural
+			translation = strings.Join(msg.Plurals, pluralSeparator)
+		}
+
+		var err error
+		if original, err = encode(original); err != nil {
+			return nil, err
+		}
+		if translation, err = encode(translation); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]string{original, translation})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0]
+	})
+	return pairs, nil
+}
+
+// hashTable returns the hash table of the specified size for the sorted pairs.
+// Collisions are resolved by double hashing as in GNU gettext.
+func hashTable(pairs [][2]string, size uint32) []uint32 {
+	table := make([]uint32, size)
+	if size == 0 {
+		return table
+	}
+
+	for idx, pair := range pairs {
+		hash := hashString(pair[0])
+		bucket := hash % size
+		if table[bucket] != 0 {
+			incr := 1 + hash%(size-2)
+			for table[bucket] != 0 {
+				if bucket >= size-incr {
+					bucket -= size - incr
+				} else {
+					bucket += incr
+				}
+			}
+		}
+		table[bucket] = uint32(idx) + 1
+	}
+	return table
+}
+
+// include reports whether msg is written to the file.
+func (e *MOEncoder) include(msg *Message) bool {
+	translated := len(msg.Translation) > 0
+	if len(msg.MsgidPlural) > 0 {
+		translated = len(msg.Plurals) > 0 && len(msg.Plurals[0]) > 0
+	}
+	return translated && (e.useFuzzy || !msg.HasFlag("fuzzy"))
+}
+
+// nextPrime returns the smallest odd number that is greater than or equal to
+// seed and that isPrime accepts, as next_prime of GNU gettext does.
+func nextPrime(seed uint32) uint32 {
+	seed |= 1
+	for !isPrime(seed) {
+		seed += 2
+	}
+	return seed
+}
+
+// isPrime is is_prime of GNU gettext, which is only meant for odd numbers
+// greater than 10. It rejects 3 and accepts 1, so msgfmt never uses a hash
+// table of size 3 for 2 or 3 strings, and neither may the encoder.
+func isPrime(n uint32) bool {
+	d := uint32(3)
+	sq := d * d
+	for sq < n && n%d != 0 {
+		d++
+		sq += 4 * d
+		d++
+	}
+	return n%d != 0
+}
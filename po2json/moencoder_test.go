@@ -0,0 +1,206 @@
+package po2json
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func (t *TestSuite) encodeMO(catalog *Catalog, configure func(*MOEncoder)) []byte {
+	buf := bytes.Buffer{}
+	encoder := NewMOEncoder(&buf)
+	if configure != nil {
+		configure(encoder)
+	}
+	t.Require().NoError(encoder.Encode(catalog))
+	return buf.Bytes()
+}
+
+// TestMOEncode_Msgfmt checks that the encoder reproduces testdata/canonical.mo
+// and testdata/canonical_be.mo. See testdata/README.md for how they were made.
+func (t *TestSuite) TestMOEncode_Msgfmt() {
+	catalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	expected, err := ioutil.ReadFile(moFilePath)
+	t.Require().NoError(err)
+	t.Equal(expected, t.encodeMO(catalog, nil))
+
+	expected, err = ioutil.ReadFile(moBEFilePath)
+	t.Require().NoError(err)
+	t.Equal(expected, t.encodeMO(catalog, func(e *MOEncoder) { e.SetByteOrder(binary.BigEndian) }))
+}
+
+// TestMOEncode_MsgfmtCollisions checks that the encoder reproduces a .mo file
+// that GNU msgfmt compiled, whose hash table has collisions. See
+// testdata/README.md.
+func (t *TestSuite) TestMOEncode_MsgfmtCollisions() {
+	expected, err := ioutil.ReadFile(msgfmtFilePath)
+	t.Require().NoError(err)
+
+	catalog, err := ParseMOBytes(expected)
+	t.Require().NoError(err)
+	fileContents := t.encodeMO(catalog, nil)
+	t.Equal(expected, fileContents)
+
+	// Count the strings that are not in the bucket of their hash.
+	words := moWords(fileContents)
+	nstrings, hashTabSize, hashTabOffset := words[2], words[5], words[6]/4
+	t.Equal(uint32(44), nstrings)
+	collisions := 0
+	for bucket := uint32(0); bucket < hashTabSize; bucket++ {
+		entry := words[hashTabOffset+bucket]
+		if entry == 0 {
+			continue
+		}
+		length, offset := words[moHeaderSize/4+2*(entry-1)], words[moHeaderSize/4+2*(entry-1)+1]
+		original := string(fileContents[offset : offset+length])
+		if idx := strings.IndexByte(original, 0); idx >= 0 {
+			original = original[:idx]
+		}
+		if hashString(original)%hashTabSize != bucket {
+			collisions++
+		}
+	}
+	t.Equal(18, collisions)
+}
+
+func (t *TestSuite) TestMOEncode_HashTableSize() {
+	// msgfmt never uses a hash table of size 3 for 2 or 3 strings, because
+	// the primality test of GNU gettext rejects 3.
+	for count, expected := range []uint32{3, 3, 5, 5, 5, 7, 11, 11} {
+		catalog := NewCatalog()
+		for idx := 0; idx < count; idx++ {
+			catalog.Add(&Message{Msgid: fmt.Sprintf("msgid %d", idx), Translation: "msgstr"})
+		}
+		t.Equal(expected, moWords(t.encodeMO(catalog, nil))[5], count)
+	}
+}
+
+func (t *TestSuite) TestMOEncode_UseFuzzy() {
+	catalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	moCatalog, err := ParseMOBytes(t.encodeMO(catalog, func(e *MOEncoder) { e.SetUseFuzzy(true) }))
+	t.Require().NoError(err)
+
+	msg, ok := moCatalog.Lookup("", "Remove the files")
+	t.True(ok)
+	t.Equal("Удалить файл", msg.Translation)
+	t.Len(moCatalog.Messages, 5)
+}
+
+func (t *TestSuite) TestMOEncode_NoHashTable() {
+	catalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	fileContents := t.encodeMO(catalog, func(e *MOEncoder) { e.SetHashTable(false) })
+	t.Equal(uint32(0), binary.LittleEndian.Uint32(fileContents[20:]))
+
+	moCatalog, err := ParseMOBytes(fileContents)
+	t.Require().NoError(err)
+
+	expected, err := ParseMOFile(moFilePath)
+	t.Require().NoError(err)
+	t.Equal(expected.Map(), moCatalog.Map())
+}
+
+func (t *TestSuite) TestMOEncode_SkippedMessages() {
+	catalog := NewCatalog()
+	catalog.Header.Translation = "Language: de\n"
+	catalog.Header.Flags = []string{"fuzzy"}
+	catalog.Add(&Message{Msgid: "Untranslated"})
+	catalog.Add(&Message{Msgid: "File", MsgidPlural: "Files", Plurals: []string{"", "Dateien"}})
+	catalog.Add(&Message{Msgid: "Fuzzy", Translation: "Unscharf", Flags: []string{"fuzzy"}})
+	catalog.Add(&Message{Msgctxt: "menu", Msgid: "Open", Translation: "Öffnen"})
+	catalog.Add(&Message{Msgctxt: "menu", Msgid: "Open", Translation: "Aufmachen"})
+	catalog.Obsolete = append(catalog.Obsolete, &Message{Msgid: "Obsolete", Translation: "Veraltet"})
+
+	moCatalog, err := ParseMOBytes(t.encodeMO(catalog, nil))
+	t.Require().NoError(err)
+	t.Equal(map[string]interface{}{
+		"": map[string]interface{}{
+			"": map[string]interface{}{
+				"Language": "de",
+			},
+		},
+		"menu": map[string]interface{}{
+			"Open": map[string]interface{}{
+				"translation": "Aufmachen",
+			},
+		},
+	}, moCatalog.Map())
+}
+
+func (t *TestSuite) TestMOEncode_Empty() {
+	fileContents := t.encodeMO(NewCatalog(), func(e *MOEncoder) { e.SetHashTable(false) })
+	t.Equal([]uint32{moMagic, 0, 0, moHeaderSize, moHeaderSize, 0, moHeaderSize}, moWords(fileContents))
+
+	fileContents = t.encodeMO(NewCatalog(), nil)
+	t.Equal([]uint32{moMagic, 0, 0, moHeaderSize, moHeaderSize, 3, moHeaderSize, 0, 0, 0}, moWords(fileContents))
+}
+
+// moWords returns the contents of a little-endian .mo file as 32-bit words.
+func moWords(fileContents []byte) []uint32 {
+	words := make([]uint32, len(fileContents)/4)
+	binary.Read(bytes.NewReader(fileContents), binary.LittleEndian, words)
+	return words
+}
+
+func (t *TestSuite) TestMOEncode_Charset() {
+	catalog := NewCatalog()
+	catalog.Header.Translation = "Content-Type: text/plain; charset=KOI8-R\n"
+	catalog.Header.Fields = []HeaderField{{Key: HeaderContentType, Value: "text/plain; charset=KOI8-R"}}
+	catalog.Add(&Message{Msgid: "Log in", Translation: "Войти"})
+
+	fileContents := t.encodeMO(catalog, nil)
+	translation, err := charmap.KOI8R.NewEncoder().String("Войти")
+	t.Require().NoError(err)
+	t.True(bytes.HasSuffix(fileContents, []byte(translation+"\x00")))
+
+	moCatalog, err := ParseMOBytes(fileContents)
+	t.Require().NoError(err)
+	msg, ok := moCatalog.Lookup("", "Log in")
+	t.True(ok)
+	t.Equal("Войти", msg.Translation)
+}
+
+func (t *TestSuite) TestMOEncode_UnsupportedCharset() {
+	catalog := NewCatalog()
+	catalog.Header.Translation = "Content-Type: text/plain; charset=UTF-7\n"
+	catalog.Header.Fields = []HeaderField{{Key: HeaderContentType, Value: "text/plain; charset=UTF-7"}}
+
+	err := NewMOEncoder(&bytes.Buffer{}).Encode(catalog)
+	t.EqualError(err, `charset "UTF-7": charset is not supported`)
+	t.True(errors.Is(err, ErrUnsupportedCharset))
+}
+
+func (t *TestSuite) TestMOEncode_WriteError() {
+	err := NewMOEncoder(errorWriter{}).Encode(NewCatalog())
+	t.EqualError(err, "write failed")
+}
+
+func (t *TestSuite) TestHashString() {
+	for s, expected := range map[string]uint32{
+		"":                       0,
+		"a":                      0x61,
+		"ab":                     0x672,
+		"Log in":                 0x53596fe,
+		"Button label\x04Log in": 0x9d9ab6e,
+		"with\x00plural":         0x7e0a8,
+	} {
+		t.Equal(expected, hashString(s), s)
+	}
+}
+
+func (t *TestSuite) TestNextPrime() {
+	// Like next_prime of GNU gettext, nextPrime accepts 1 and rejects 3.
+	for seed, expected := range map[uint32]uint32{0: 1, 1: 1, 2: 5, 3: 5, 4: 5, 6: 7, 8: 11, 9: 11, 13: 13, 24: 29, 25: 29} {
+		t.Equal(expected, nextPrime(seed), seed)
+	}
+}
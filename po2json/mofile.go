@@ -0,0 +1,312 @@
+package po2json
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+)
+
+// MOFile is a read-only .mo file that is mapped into memory. Messages are
+// looked up in place, with the hash table of the file if it has one and by
+// binary search otherwise, and only the strings of the messages that are
+// returned are copied onto the heap.
+//
+// A MOFile is safe for concurrent use. It must not be used after Close.
+type MOFile struct {
+	mr     *moReader
+	header Header
+	// sysdep contains the expanded system dependent messages, which can't be
+	// looked up in place, keyed by their original string.
+	sysdep map[string]*Message
+	// sysdepKeys contains the keys of sysdep in the order of the file.
+	sysdepKeys []string
+	// enc is the charset of the file, or nil for UTF-8.
+	enc   encoding.Encoding
+	unmap func() error
+}
+
+// OpenMOFile maps the compiled .mo file at filePath into memory.
+//
+// An error is returned if the file doesn't exist or can't be mapped,
+// or if the file is in an invalid format. Errors in the format are
+// reported as a *MOError that contains the file path.
+func OpenMOFile(filePath string) (*MOFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, unmap, err := mapFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newMOFile(filePath, data, unmap)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	return f, nil
+}
+
+// newMOFile checks the tables of the .mo file in data and reads its header
+// and system dependent strings. unmap releases data.
+func newMOFile(file string, data []byte, unmap func() error) (*MOFile, error) {
+	mr, err := newMOReader(file, data)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := 0; idx < mr.nstrings; idx++ {
+		if _, err := mr.bytes(mr.origTabOffset, idx); err != nil {
+			return nil, err
+		}
+		if _, err := mr.bytes(mr.transTabOffset, idx); err != nil {
+			return nil, err
+		}
+	}
+	if mr.hashTabSize > 2 {
+		if _, ok := mr.uint32(mr.hashTabOffset + 4*(mr.hashTabSize-1)); !ok {
+			return nil, mr.errorf(20, "Invalid .mo file. Found hash table of size %d beyond the end of the file.", mr.hashTabSize)
+		}
+	}
+
+	f := &MOFile{mr: mr, sysdep: map[string]*Message{}, unmap: unmap}
+	if idx, ok := f.find(""); ok {
+		translation, _ := mr.bytes(mr.transTabOffset, idx)
+		if f.enc, err = mr.charset(string(translation)); err != nil {
+			return nil, err
+		}
+		msg, err := f.message(idx)
+		if err != nil {
+			return nil, err
+		}
+		if f.header, err = mr.header(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	sysdepPairs, err := mr.sysdepPairs()
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range sysdepPairs {
+		if pair, err = f.decode(pair); err != nil {
+			return nil, err
+		}
+		original := pair[0]
+		if idx := strings.Index(original, pluralSeparator); idx >= 0 {
+			original = original[:idx]
+		}
+		f.sysdep[original] = newMOMessage(pair[0], pair[1])
+		f.sysdepKeys = append(f.sysdepKeys, original)
+	}
+
+	return f, nil
+}
+
+// Close unmaps the file.
+func (f *MOFile) Close() error {
+	f.mr.data = nil
+	return f.unmap()
+}
+
+// Header returns a deep copy of the header of the file. It is the zero Header
+// if the file does not have one.
+func (f *MOFile) Header() Header {
+	return f.header.Clone()
+}
+
+// Lookup returns the message with the specified msgctxt and msgid. The header
+// is returned for an empty msgctxt and msgid.
+func (f *MOFile) Lookup(msgctxt string, msgid string) (*Message, bool) {
+	if len(msgctxt) == 0 && len(msgid) == 0 {
+		return &f.header.Message, true
+	}
+
+	key := msgid
+	if len(msgctxt) > 0 {
+		key = msgctxt + contextSeparator + msgid
+	}
+	if msg, ok := f.sysdep[key]; ok {
+		return msg, true
+	}
+
+	if f.enc != nil {
+		var err error
+		if key, err = f.enc.NewEncoder().String(key); err != nil {
+			return nil, false
+		}
+	}
+
+	idx, ok := f.find(key)
+	if !ok {
+		return nil, false
+	}
+
+	msg, err := f.message(idx)
+	if err != nil {
+		return nil, false
+	}
+	return msg, true
+}
+
+// HasContext reports whether the file contains any message with the
+// specified msgctxt. The empty msgctxt, which contains the header, is always
+// present.
+func (f *MOFile) HasContext(msgctxt string) bool {
+	if len(msgctxt) == 0 {
+		return true
+	}
+
+	for _, msg := range f.sysdep {
+		if msg.Msgctxt == msgctxt {
+			return true
+		}
+	}
+
+	prefix := msgctxt + contextSeparator
+	if f.enc != nil {
+		var err error
+		if prefix, err = f.enc.NewEncoder().String(prefix); err != nil {
+			return false
+		}
+	}
+
+	idx := sort.Search(f.mr.nstrings, func(idx int) bool {
+		return compareOriginal(f.original(idx), prefix) >= 0
+	})
+	return idx < f.mr.nstrings && bytes.HasPrefix(f.original(idx), []byte(prefix))
+}
+
+// Messages calls fn with every message of the file other than the header, in
+// the order of Catalog, and returns the first error returned by fn. Each
+// message is only copied out of the file when fn is called with it, so that
+// the file is never copied onto the heap as a whole.
+//
+// An error is also returned if a message can't be decoded.
+func (f *MOFile) Messages(fn func(msg *Message) error) error {
+	for idx := 0; idx < f.mr.nstrings; idx++ {
+		if len(f.original(idx)) == 0 {
+			continue
+		}
+
+		msg, err := f.message(idx)
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range f.sysdepKeys {
+		msg := f.sysdep[key].Clone()
+		if err := fn(&msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Catalog parses the whole file into a Catalog that does not share any memory
+// with the file.
+func (f *MOFile) Catalog() (*Catalog, error) {
+	return parseMO(f.mr.file, f.mr.data)
+}
+
+// find returns the index of the message whose original string, up to the
+// msgid_plural, is key.
+func (f *MOFile) find(key string) (int, bool) {
+	if f.mr.hashTabSize > 2 {
+		return f.findHashed(key)
+	}
+
+	idx := sort.Search(f.mr.nstrings, func(idx int) bool {
+		return compareOriginal(f.original(idx), key) >= 0
+	})
+	if idx < f.mr.nstrings && compareOriginal(f.original(idx), key) == 0 {
+		return idx, true
+	}
+	return 0, false
+}
+
+// findHashed looks key up in the hash table in the same way as GNU gettext.
+func (f *MOFile) findHashed(key string) (int, bool) {
+	size := uint32(f.mr.hashTabSize)
+	hash := hashString(key)
+	bucket := hash % size
+	incr := 1 + hash%(size-2)
+	for probes := uint32(0); probes < size; probes++ {
+		entry, _ := f.mr.uint32(f.mr.hashTabOffset + 4*int(bucket))
+		if entry == 0 {
+			return 0, false
+		}
+
+		// Entries beyond the static strings refer to system dependent
+		// strings, which are looked up separately.
+		if idx := entry - 1; idx < f.mr.nstrings && compareOriginal(f.original(idx), key) == 0 {
+			return idx, true
+		}
+
+		if bucket >= size-incr {
+			bucket -= size - incr
+		} else {
+			bucket += incr
+		}
+	}
+	return 0, false
+}
+
+// original returns the original string of the idx-th message without
+// copying it.
+func (f *MOFile) original(idx int) []byte {
+	original, _ := f.mr.bytes(f.mr.origTabOffset, idx)
+	return original
+}
+
+// message copies the idx-th message out of the file.
+func (f *MOFile) message(idx int) (*Message, error) {
+	translation, _ := f.mr.bytes(f.mr.transTabOffset, idx)
+	pair, err := f.decode([2]string{string(f.original(idx)), string(translation)})
+	if err != nil {
+		return nil, err
+	}
+	return newMOMessage(pair[0], pair[1]), nil
+}
+
+// decode transcodes the original and translated strings to UTF-8.
+func (f *MOFile) decode(pair [2]string) ([2]string, error) {
+	if f.enc == nil {
+		return pair, nil
+	}
+
+	decoder := f.enc.NewDecoder()
+	for idx := range pair {
+		var err error
+		if pair[idx], err = decoder.String(pair[idx]); err != nil {
+			return pair, err
+		}
+	}
+	return pair, nil
+}
+
+// compareOriginal compares the original string of a message, up to its
+// msgid_plural, with key as strcmp does.
+func compareOriginal(original []byte, key string) int {
+	if idx := bytes.IndexByte(original, 0); idx >= 0 {
+		original = original[:idx]
+	}
+
+	switch {
+	case string(original) < key:
+		return -1
+	case string(original) > key:
+		return 1
+	}
+	return 0
+}
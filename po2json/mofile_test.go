@@ -0,0 +1,201 @@
+package po2json
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// writeMO writes fileContents to a temporary file and opens it.
+func (t *TestSuite) writeMO(fileContents []byte) *MOFile {
+	filePath := filepath.Join(t.T().TempDir(), "messages.mo")
+	t.Require().NoError(ioutil.WriteFile(filePath, fileContents, 0o644))
+
+	f, err := OpenMOFile(filePath)
+	t.Require().NoError(err)
+	t.T().Cleanup(func() { f.Close() })
+	return f
+}
+
+// assertMOFile checks that every message of catalog can be looked up in f.
+func (t *TestSuite) assertMOFile(f *MOFile, catalog *Catalog) {
+	for _, expected := range catalog.Messages {
+		msg, ok := f.Lookup(expected.Msgctxt, expected.Msgid)
+		t.Require().True(ok, expected.Msgid)
+		t.Equal(expected, msg)
+	}
+}
+
+func (t *TestSuite) TestOpenMOFile_Valid() {
+	for _, filePath := range []string{moFilePath, moBEFilePath} {
+		f, err := OpenMOFile(filePath)
+		t.Require().NoError(err)
+
+		catalog, err := ParseMOFile(filePath)
+		t.Require().NoError(err)
+
+		t.Equal(catalog.Header, f.Header())
+		t.assertMOFile(f, catalog)
+
+		msg, ok := f.Lookup("", "")
+		t.True(ok)
+		t.Equal(catalog.Header.Translation, msg.Translation)
+
+		_, ok = f.Lookup("", "Remove the files")
+		t.False(ok)
+		_, ok = f.Lookup("Button label", "Log out")
+		t.False(ok)
+
+		t.NoError(f.Close())
+	}
+}
+
+func (t *TestSuite) TestOpenMOFile_NoHashTable() {
+	catalog, err := ParseFile(canonicalPoFilePath)
+	t.Require().NoError(err)
+
+	expected, err := ParseMOFile(moFilePath)
+	t.Require().NoError(err)
+
+	f := t.writeMO(t.encodeMO(catalog, func(e *MOEncoder) { e.SetHashTable(false) }))
+	t.assertMOFile(f, expected)
+
+	_, ok := f.Lookup("", "Remove the files")
+	t.False(ok)
+}
+
+func (t *TestSuite) TestOpenMOFile_HashCollisions() {
+	catalog := NewCatalog()
+	for _, msgid := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		catalog.Add(&Message{Msgid: msgid, Translation: msgid + msgid})
+		catalog.Add(&Message{Msgctxt: msgid, Msgid: msgid, Translation: msgid})
+	}
+
+	f := t.writeMO(t.encodeMO(catalog, nil))
+	t.assertMOFile(f, catalog)
+
+	_, ok := f.Lookup("", "m")
+	t.False(ok)
+}
+
+func (t *TestSuite) TestOpenMOFile_HasContext() {
+	f := t.writeMO(buildMO(binary.LittleEndian, 0,
+		[2]string{"Open", "Öffnen"},
+		[2]string{"menu\x04File\x00Files", "Datei\x00Dateien"},
+		[2]string{"menus\x04Open", "Öffnen"},
+	))
+
+	t.True(f.HasContext(""))
+	t.True(f.HasContext("menu"))
+	t.True(f.HasContext("menus"))
+	t.False(f.HasContext("men"))
+	t.False(f.HasContext("toolbar"))
+
+	msg, ok := f.Lookup("menu", "File")
+	t.Require().True(ok)
+	t.Equal("Files", msg.MsgidPlural)
+	t.Equal([]string{"Datei", "Dateien"}, msg.Plurals)
+}
+
+func (t *TestSuite) TestOpenMOFile_Charset() {
+	original, err := charmap.KOI8R.NewEncoder().String("Войти")
+	t.Require().NoError(err)
+
+	f := t.writeMO(buildMO(binary.LittleEndian, 0,
+		[2]string{"", "Content-Type: text/plain; charset=KOI8-R\n"},
+		[2]string{original, "Log in"},
+	))
+
+	msg, ok := f.Lookup("", "Войти")
+	t.Require().True(ok)
+	t.Equal("Войти", msg.Msgid)
+	t.Equal("Log in", msg.Translation)
+
+	_, ok = f.Lookup("", "日本語")
+	t.False(ok)
+}
+
+func (t *TestSuite) TestOpenMOFile_SysdepStrings() {
+	f := t.writeMO(sysdepMO())
+
+	msg, ok := f.Lookup("", "%<PRIu64> files")
+	t.Require().True(ok)
+	t.Equal("%Id <PRIu64> Dateien", msg.Translation)
+}
+
+func (t *TestSuite) TestOpenMOFile_Catalog() {
+	f, err := OpenMOFile(moFilePath)
+	t.Require().NoError(err)
+
+	catalog, err := f.Catalog()
+	t.Require().NoError(err)
+	t.NoError(f.Close())
+
+	expected, err := ParseMOFile(moFilePath)
+	t.Require().NoError(err)
+	t.Equal(expected.Map(), catalog.Map())
+}
+
+func (t *TestSuite) TestOpenMOFile_Messages() {
+	moFileContents, err := ioutil.ReadFile(moFilePath)
+	t.Require().NoError(err)
+
+	for _, fileContents := range [][]byte{moFileContents, sysdepMO()} {
+		f := t.writeMO(fileContents)
+		expected, err := f.Catalog()
+		t.Require().NoError(err)
+
+		messages := []*Message{}
+		t.NoError(f.Messages(func(msg *Message) error {
+			messages = append(messages, msg)
+			return nil
+		}))
+		t.Equal(expected.Messages, messages)
+	}
+
+	f := t.writeMO(moFileContents)
+	count := 0
+	err = f.Messages(func(msg *Message) error {
+		count++
+		return errors.New("stop")
+	})
+	t.EqualError(err, "stop")
+	t.Equal(1, count)
+}
+
+func (t *TestSuite) TestOpenMOFile_FileNotFound() {
+	f, err := OpenMOFile("./this/doesnt/exist")
+	t.Nil(f)
+	t.True(os.IsNotExist(err))
+}
+
+func (t *TestSuite) TestOpenMOFile_Invalid() {
+	dir := t.T().TempDir()
+
+	hashTable := buildMO(binary.LittleEndian, 0, [2]string{"Log in", "Войти"})
+	binary.LittleEndian.PutUint32(hashTable[20:], 5)
+	binary.LittleEndian.PutUint32(hashTable[24:], uint32(len(hashTable)-8))
+
+	for expected, fileContents := range map[string][]byte{
+		"offset 0: Invalid .mo file. Found magic number 0x00000000, expected 0x950412de.":     make([]byte, moHeaderSize),
+		"offset 28: Invalid .mo file. Found string table entry 0 beyond the end of the file.": buildMO(binary.LittleEndian, 0, [2]string{"Log in", "Войти"})[:moHeaderSize],
+		"offset 20: Invalid .mo file. Found hash table of size 5 beyond the end of the file.": hashTable,
+		`offset 0: Invalid .mo file. Found unsupported charset "UTF-7": charset is not supported`: buildMO(binary.LittleEndian, 0,
+			[2]string{"", "Content-Type: text/plain; charset=UTF-7\n"},
+		),
+	} {
+		filePath := filepath.Join(dir, "messages.mo")
+		t.Require().NoError(ioutil.WriteFile(filePath, fileContents, 0o644))
+
+		f, err := OpenMOFile(filePath)
+		t.Nil(f)
+		t.EqualError(err, filePath+": "+expected)
+
+		var moErr *MOError
+		t.True(errors.As(err, &moErr), expected)
+	}
+}
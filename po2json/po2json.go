@@ -3,21 +3,52 @@ package po2json
 // PO file format documentation: https://www.gnu.org/software/gettext/manual/html_node/PO-Files.html
 import (
 	"bytes"
-	"errors"
-	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
+	"os"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type translationKey struct {
-	Msgctxt      strings.Builder
-	Msgid        strings.Builder
-	Msgstr       strings.Builder
-	MsgidPlural  strings.Builder
-	MsgstrPlural []*strings.Builder
+	Msgctxt            strings.Builder
+	Msgid              strings.Builder
+	Msgstr             strings.Builder
+	MsgidPlural        strings.Builder
+	MsgstrPlural       map[int]*strings.Builder
+	MsgstrPluralIndex  int
+	TranslatorComments []string
+	ExtractedComments  []string
+	References         []string
+	Flags              []string
+	Previous           previousKey
+	Obsolete           bool
+	// Position is the position of the first msgctxt or msgid line.
+	Position position
+	// MsgstrPosition is the position of the msgstr line.
+	MsgstrPosition position
+	// MsgstrSegments records where each string of the msgstr came from so
+	// that errors in the header can be attributed to the right line.
+	MsgstrSegments []segment
+}
+
+// segment records the position of a string that was appended to a builder
+// at the given offset.
+type segment struct {
+	offset   int
+	position position
+}
+
+// previousKey holds the "#|" previous msgctxt, msgid, and msgid_plural that
+// msgmerge writes above fuzzy entries.
+type previousKey struct {
+	Msgctxt     strings.Builder
+	Msgid       strings.Builder
+	MsgidPlural strings.Builder
+	State       stateEnum
 }
 
 type stateEnum int
@@ -41,29 +72,40 @@ var (
 		stateMsgstrPlural: "msgstr_plural",
 	}
 
-	regexComment        = regexp.MustCompile(`^#.*$`)
-	regexEmpty          = regexp.MustCompile(`^\s*$`)
-	regexMsgctxt        = regexp.MustCompile(`^msgctxt\s+(".*")$`)
-	regexMsgid          = regexp.MustCompile(`^msgid\s+(".*")$`)
-	regexMsgstr         = regexp.MustCompile(`^msgstr\s+(".*")$`)
-	regexMsgidPlural    = regexp.MustCompile(`^msgid_plural\s+(".*")$`)
-	regexMsgstrPlural   = regexp.MustCompile(`^msgstr\[\d+\]\s+(".*")$`)
-	regexString         = regexp.MustCompile(`^(".*")$`)
-	regexHeaderKeyValue = regexp.MustCompile(`([a-zA-Z0-9-]+)\s*:\s*(.*?)(?:\n|\z)`)
+	regexComment           = regexp.MustCompile(`^#.*$`)
+	regexExtractedComment  = regexp.MustCompile(`^#\.\s?(.*)$`)
+	regexReference         = regexp.MustCompile(`^#:\s*(.*)$`)
+	regexFlags             = regexp.MustCompile(`^#,\s*(.*)$`)
+	regexPrevious          = regexp.MustCompile(`^#\|\s*(.*)$`)
+	regexObsolete          = regexp.MustCompile(`^#~(.*)$`)
+	regexTranslatorComment = regexp.MustCompile(`^#\s?(.*)$`)
+	regexEmpty             = regexp.MustCompile(`^\s*$`)
+	regexMsgctxt           = regexp.MustCompile(`^msgctxt\s+(".*")$`)
+	regexMsgid             = regexp.MustCompile(`^msgid\s+(".*")$`)
+	regexMsgstr            = regexp.MustCompile(`^msgstr\s+(".*")$`)
+	regexMsgidPlural       = regexp.MustCompile(`^msgid_plural\s+(".*")$`)
+	regexMsgstrPlural      = regexp.MustCompile(`^msgstr\[(-?\d+)\]\s+(".*")$`)
+	regexString            = regexp.MustCompile(`^(".*")$`)
+	regexHeaderKeyValue    = regexp.MustCompile(`([a-zA-Z0-9-]+)\s*:\s*(.*?)(?:\n|\z)`)
 )
 
 type loader struct {
 	key        translationKey
 	state      stateEnum
 	nextStates map[stateEnum]bool
-	poJSON     map[string]interface{}
+	catalog    *Catalog
+	inObsolete bool
+	file       string
+	line       int
+	text       []byte
 }
 
-func newLoader() *loader {
+func newLoader(file string) *loader {
 	return &loader{
 		state:      stateUnspecified,
 		nextStates: map[stateEnum]bool{stateMsgctxt: true, stateMsgid: true},
-		poJSON:     map[string]interface{}{},
+		catalog:    NewCatalog(),
+		file:       file,
 	}
 }
 
@@ -73,12 +115,40 @@ func newLoader() *loader {
 // An error is returned if the file doesn't exist
 // or if the file is in an invalid format.
 func LoadFile(filePath string) (map[string]interface{}, error) {
-	fileContents, err := ioutil.ReadFile(filePath)
+	catalog, err := ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.Map(), nil
+}
+
+// LoadFS reads the contents of the .po file at filePath within fsys and
+// loads it into a map[string]interface{}.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format.
+func LoadFS(fsys fs.FS, filePath string) (map[string]interface{}, error) {
+	catalog, err := ParseFS(fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.Map(), nil
+}
+
+// LoadReader reads a .po file from r and loads it into
+// a map[string]interface{}.
+//
+// An error is returned if reading from r fails
+// or if the file is in an invalid format.
+func LoadReader(r io.Reader) (map[string]interface{}, error) {
+	catalog, err := ParseReader(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return LoadBytes(fileContents)
+	return catalog.Map(), nil
 }
 
 // LoadString loads a string representation of a .po file into
@@ -96,215 +166,568 @@ func LoadString(fileContents string) (map[string]interface{}, error) {
 // An error is returned if the file doesn't exist
 // or if the file is in an invalid format.
 func LoadBytes(fileContents []byte) (map[string]interface{}, error) {
-	l := newLoader()
+	catalog, err := ParseBytes(fileContents)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.Map(), nil
+}
+
+// ParseFile reads the contents of a .po file and parses it into a Catalog.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format. Errors in the format are
+// reported as a *ParseError that contains the file path.
+func ParseFile(filePath string) (*Catalog, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseReader(filePath, file)
+}
+
+// ParseFS reads the contents of the .po file at filePath within fsys and
+// parses it into a Catalog.
+//
+// An error is returned if the file doesn't exist
+// or if the file is in an invalid format. Errors in the format are
+// reported as a *ParseError that contains the file path.
+func ParseFS(fsys fs.FS, filePath string) (*Catalog, error) {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseReader(filePath, file)
+}
+
+// ParseString parses a string representation of a .po file into a Catalog.
+//
+// An error is returned if the file is in an invalid format.
+func ParseString(fileContents string) (*Catalog, error) {
+	return ParseBytes([]byte(fileContents))
+}
+
+// ParseBytes parses a byte slice representation of a .po file into a Catalog.
+//
+// An error is returned if the file is in an invalid format. Errors in the
+// format are reported as a *ParseError.
+func ParseBytes(fileContents []byte) (*Catalog, error) {
+	return parseReader("", bytes.NewReader(fileContents))
+}
+
+// ParseReader reads a .po file from r and parses it into a Catalog.
+//
+// The file is parsed one line at a time as it is read, so only the header
+// entry, the current line, and the resulting Catalog are held in memory.
+// The file is transcoded to UTF-8 according to its byte order mark or the
+// charset in the Content-Type header.
+//
+// An error is returned if reading from r fails or if the file is in an
+// invalid format. Errors in the format are reported as a *ParseError.
+func ParseReader(r io.Reader) (*Catalog, error) {
+	return parseReader("", r)
+}
+
+func parseReader(file string, r io.Reader) (*Catalog, error) {
+	l := newLoader(file)
+	reader, err := l.newUTF8Reader(r)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, line := range bytes.Split(fileContents, []byte("\n")) {
-		// Ignore the line if it is a comment.
-		// We expect the next line to be anything.
-		if regexComment.Match(line) {
-			continue
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
 		}
 
-		// If this is an empty line, then we expect the next
-		// non-empty non-comment line to be msgctxt or msgid.
-		if regexEmpty.Match(line) {
-			if err := l.addKeyToJson(); err != nil {
-				return nil, err
-			}
+		l.line = lineNumber
+		l.text = bytes.TrimSuffix(line, []byte("\n"))
+		if err := l.parseLine(l.text); err != nil {
+			return nil, err
+		}
 
-			l.key = translationKey{}
-			l.state = stateUnspecified
-			l.nextStates = map[stateEnum]bool{stateMsgctxt: true, stateMsgid: true}
-			continue
+		if readErr == io.EOF {
+			break
 		}
+	}
 
-		// If this is a msgctxt line, then:
-		// 1) msgctxt must be a valid state.
-		// 2) We expect the next line to be either a string or a msgid.
-		if submatch := regexMsgctxt.FindSubmatch(line); submatch != nil {
-			l.state = stateMsgctxt
-			if err := l.expectState(); err != nil {
-				return nil, err
-			}
+	if err := l.addKeyToCatalog(); err != nil {
+		return nil, err
+	}
 
-			l.nextStates = map[stateEnum]bool{stateMsgid: true}
+	return l.catalog, nil
+}
 
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
-			l.key.Msgctxt.WriteString(msg)
-			continue
+// parseLine parses a single line of a .po file and updates the state of the
+// loader accordingly.
+func (l *loader) parseLine(line []byte) error {
+	// If this is an obsolete line, then parse the remainder of the line as
+	// if it were active.
+	if submatch := regexObsolete.FindSubmatch(line); submatch != nil {
+		return l.parseObsoleteLine(submatch[1])
+	}
+
+	// If this is a comment, then store it on the current key.
+	// We expect the next line to be anything.
+	if regexComment.Match(line) {
+		if err := l.addComment(line); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	// If this is an empty line, then we expect the next
+	// non-empty non-comment line to be msgctxt or msgid.
+	if regexEmpty.Match(line) {
+		if err := l.addKeyToCatalog(); err != nil {
+			return err
 		}
 
-		// If this is a msgid line, then:
-		// 1) msgid must be a valid state.
-		// 2) We expect the next line to be either a string, msgstr, or msgid_plural.
-		if submatch := regexMsgid.FindSubmatch(line); submatch != nil {
-			l.state = stateMsgid
-			if err := l.expectState(); err != nil {
-				return nil, err
-			}
+		l.key = translationKey{}
+		l.state = stateUnspecified
+		l.nextStates = map[stateEnum]bool{stateMsgctxt: true, stateMsgid: true}
+		return nil
+	}
 
-			l.nextStates = map[stateEnum]bool{stateMsgidPlural: true, stateMsgstr: true}
+	// Obsolete and active lines cannot be mixed within a single key.
+	if l.key.Obsolete != l.inObsolete {
+		return l.errorf(l.position(line, 0), "Invalid .po file. Found obsolete and active lines in the same entry.")
+	}
 
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
-			l.key.Msgid.WriteString(msg)
-			continue
+	// If this is a msgctxt line, then:
+	// 1) msgctxt must be a valid state.
+	// 2) We expect the next line to be either a string or a msgid.
+	if loc := regexMsgctxt.FindSubmatchIndex(line); loc != nil {
+		l.state = stateMsgctxt
+		if err := l.expectState(line); err != nil {
+			return err
 		}
 
-		// If this is a msgstr line, then:
-		// 1) msgstr must be a valid state.
-		// 2) We expect the next line to be either a string or blank.
-		if submatch := regexMsgstr.FindSubmatch(line); submatch != nil {
-			l.state = stateMsgstr
-			if err := l.expectState(); err != nil {
-				return nil, err
-			}
+		l.nextStates = map[stateEnum]bool{stateMsgid: true}
+		l.key.Position = l.position(line, 0)
 
-			l.nextStates = map[stateEnum]bool{stateMsgidPlural: true, stateMsgstr: true}
+		msg, err := l.unquote(line, loc[2], loc[3])
+		if err != nil {
+			return err
+		}
+		l.key.Msgctxt.WriteString(msg)
+		return nil
+	}
 
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
-			l.key.Msgstr.WriteString(msg)
-			continue
+	// If this is a msgid line, then:
+	// 1) msgid must be a valid state.
+	// 2) We expect the next line to be either a string, msgstr, or msgid_plural.
+	if loc := regexMsgid.FindSubmatchIndex(line); loc != nil {
+		l.state = stateMsgid
+		if err := l.expectState(line); err != nil {
+			return err
 		}
 
-		// If this is a msgid_plural line, then:
-		// 1) msgid_plural must be a valid state.
-		// 2) We expect the next line to be either a string or msgstr_plural.
-		if submatch := regexMsgidPlural.FindSubmatch(line); submatch != nil {
-			l.state = stateMsgidPlural
-			if err := l.expectState(); err != nil {
-				return nil, err
-			}
+		l.nextStates = map[stateEnum]bool{stateMsgidPlural: true, stateMsgstr: true}
+		if l.key.Position.line == 0 {
+			l.key.Position = l.position(line, 0)
+		}
 
-			l.nextStates = map[stateEnum]bool{stateMsgstrPlural: true}
+		msg, err := l.unquote(line, loc[2], loc[3])
+		if err != nil {
+			return err
+		}
+		l.key.Msgid.WriteString(msg)
+		return nil
+	}
 
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
-			l.key.MsgidPlural.WriteString(msg)
-			continue
+	// If this is a msgstr line, then:
+	// 1) msgstr must be a valid state.
+	// 2) We expect the next line to be either a string or blank.
+	if loc := regexMsgstr.FindSubmatchIndex(line); loc != nil {
+		l.state = stateMsgstr
+		if err := l.expectState(line); err != nil {
+			return err
 		}
 
-		// If this is a msgstr_plural line, then:
-		// 1) msgstr_plural must be a valid state.
-		// 2) We expect the next line to be either a string, msgstr_plural, or blank.
-		if submatch := regexMsgstrPlural.FindSubmatch(line); submatch != nil {
-			l.state = stateMsgstrPlural
-			if err := l.expectState(); err != nil {
-				return nil, err
-			}
+		l.nextStates = map[stateEnum]bool{stateMsgidPlural: true, stateMsgstr: true}
+		l.key.MsgstrPosition = l.position(line, 0)
 
-			l.nextStates = map[stateEnum]bool{stateMsgstrPlural: true}
+		msg, err := l.unquote(line, loc[2], loc[3])
+		if err != nil {
+			return err
+		}
+		l.appendMsgstr(line, loc[2], msg)
+		return nil
+	}
 
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
-			plural := strings.Builder{}
-			plural.WriteString(msg)
-			l.key.MsgstrPlural = append(l.key.MsgstrPlural, &plural)
-			continue
+	// If this is a msgid_plural line, then:
+	// 1) msgid_plural must be a valid state.
+	// 2) We expect the next line to be either a string or msgstr_plural.
+	if loc := regexMsgidPlural.FindSubmatchIndex(line); loc != nil {
+		l.state = stateMsgidPlural
+		if err := l.expectState(line); err != nil {
+			return err
 		}
 
-		// If this is a string continuation, then:
-		// 1) Append the string to the existing string as determined by the
-		// current_state.
-		if submatch := regexString.FindSubmatch(line); submatch != nil {
-			msg, err := strconv.Unquote(string(submatch[1]))
-			if err != nil {
-				return nil, err
-			}
+		l.nextStates = map[stateEnum]bool{stateMsgstrPlural: true}
 
-			switch l.state {
-			case stateMsgctxt:
-				l.key.Msgctxt.WriteString(msg)
-			case stateMsgid:
-				l.key.Msgid.WriteString(msg)
-			case stateMsgstr:
-				l.key.Msgstr.WriteString(msg)
-			case stateMsgidPlural:
-				l.key.MsgidPlural.WriteString(msg)
-			case stateMsgstrPlural:
-				l.key.MsgstrPlural[len(l.key.MsgstrPlural)-1].WriteString(msg)
-			case stateUnspecified:
-				return nil, errors.New("Encountered invalid state. Please ensure the input file is in a valid .po format.")
-			}
-			continue
+		msg, err := l.unquote(line, loc[2], loc[3])
+		if err != nil {
+			return err
 		}
+		l.key.MsgidPlural.WriteString(msg)
+		return nil
 	}
 
-	err := l.addKeyToJson()
+	// If this is a msgstr_plural line, then:
+	// 1) msgstr_plural must be a valid state.
+	// 2) The index must be non-negative and must not have been seen before.
+	// 3) We expect the next line to be either a string, msgstr_plural, or blank.
+	if loc := regexMsgstrPlural.FindSubmatchIndex(line); loc != nil {
+		l.state = stateMsgstrPlural
+		if err := l.expectState(line); err != nil {
+			return err
+		}
+
+		l.nextStates = map[stateEnum]bool{stateMsgstrPlural: true}
+
+		idx, err := l.pluralIndex(line, loc[2], loc[3])
+		if err != nil {
+			return err
+		}
+
+		msg, err := l.unquote(line, loc[4], loc[5])
+		if err != nil {
+			return err
+		}
+		plural := strings.Builder{}
+		plural.WriteString(msg)
+		l.key.MsgstrPlural[idx] = &plural
+		l.key.MsgstrPluralIndex = idx
+		return nil
+	}
+
+	// If this is a string continuation, then:
+	// 1) Append the string to the existing string as determined by the
+	// current_state.
+	if loc := regexString.FindSubmatchIndex(line); loc != nil {
+		msg, err := l.unquote(line, loc[2], loc[3])
+		if err != nil {
+			return err
+		}
+
+		switch l.state {
+		case stateMsgctxt:
+			l.key.Msgctxt.WriteString(msg)
+		case stateMsgid:
+			l.key.Msgid.WriteString(msg)
+		case stateMsgstr:
+			l.appendMsgstr(line, loc[2], msg)
+		case stateMsgidPlural:
+			l.key.MsgidPlural.WriteString(msg)
+		case stateMsgstrPlural:
+			l.key.MsgstrPlural[l.key.MsgstrPluralIndex].WriteString(msg)
+		case stateUnspecified:
+			return l.errorf(l.position(line, 0), "Encountered invalid state. Please ensure the input file is in a valid .po format.")
+		}
+		return nil
+	}
+	return nil
+}
+
+// unquote unquotes the string found between start and end of line.
+func (l *loader) unquote(line []byte, start int, end int) (string, error) {
+	msg, err := Unquote(string(line[start:end]))
 	if err != nil {
-		return nil, err
+		offset := start
+		if syntaxErr, ok := err.(*SyntaxError); ok {
+			offset += syntaxErr.Offset
+		}
+		parseErr := l.errorf(l.position(line, offset), "Invalid .po file. Found invalid string %s", line[start:end])
+		parseErr.Err = err
+		return "", parseErr
 	}
+	return msg, nil
+}
+
+// appendMsgstr appends msg, which was unquoted from the string starting at
+// offset within line, to the msgstr of the current key.
+func (l *loader) appendMsgstr(line []byte, offset int, msg string) {
+	l.key.MsgstrSegments = append(l.key.MsgstrSegments, segment{
+		offset:   l.key.Msgstr.Len(),
+		position: l.position(line, offset+1),
+	})
+	l.key.Msgstr.WriteString(msg)
+}
 
-	return l.poJSON, nil
+// msgstrPosition returns the position of the byte at offset within the
+// msgstr of the current key.
+func (l *loader) msgstrPosition(offset int) position {
+	pos := l.key.MsgstrPosition
+	for _, seg := range l.key.MsgstrSegments {
+		if seg.offset > offset {
+			break
+		}
+		pos = seg.position
+		pos.column += utf8.RuneCountInString(l.key.Msgstr.String()[seg.offset:offset])
+	}
+	return pos
 }
 
-func (l *loader) addKeyToJson() error {
-	msgctxt := l.key.Msgctxt.String()
-	msgid := l.key.Msgid.String()
-	msgstr := l.key.Msgstr.String()
-	msgstrPlural := []string{}
-	for _, plural := range l.key.MsgstrPlural {
-		msgstrPlural = append(msgstrPlural, plural.String())
+// addKeyToCatalog adds the current key to the catalog. Keys without a msgid,
+// such as a trailing block of comments, are discarded.
+func (l *loader) addKeyToCatalog() error {
+	if l.key.Position.line == 0 {
+		return nil
 	}
 
-	if _, ok := l.poJSON[msgctxt]; !ok {
-		l.poJSON[msgctxt] = map[string]interface{}{}
+	msg, err := l.newMessage()
+	if err != nil {
+		return err
 	}
-	msgctxtObj := l.poJSON[msgctxt].(map[string]interface{})
 
-	if _, ok := msgctxtObj[msgid]; !ok {
-		msgctxtObj[msgid] = map[string]interface{}{}
+	if l.key.Obsolete {
+		l.catalog.Obsolete = append(l.catalog.Obsolete, msg)
+		return nil
 	}
-	msgidObj := msgctxtObj[msgid].(map[string]interface{})
 
-	if len(msgstr) > 0 {
-		if len(msgid) == 0 {
-			for _, submatch := range regexHeaderKeyValue.FindAllStringSubmatch(msgstr, -1) {
-				key := submatch[1]
-				if _, ok := msgidObj[key]; ok {
-					return fmt.Errorf(`Invalid .po file. Found duplicate header key "%s".`, key)
-				}
-				msgidObj[key] = submatch[2]
-			}
-		} else {
-			if _, ok := msgidObj["translation"]; ok {
-				return fmt.Errorf(`Invalid .po file. Found duplicate msgstr for msgid "%s".`, msgid)
+	if len(msg.Msgctxt) == 0 && len(msg.Msgid) == 0 {
+		return l.addHeader(msg)
+	}
+
+	existing, ok := l.catalog.Lookup(msg.Msgctxt, msg.Msgid)
+	if !ok {
+		l.catalog.Add(msg)
+		return nil
+	}
+
+	if len(msg.Translation) > 0 {
+		if len(existing.Translation) > 0 {
+			return l.errorf(l.key.MsgstrPosition, `Invalid .po file. Found duplicate msgstr for msgid "%s".`, msg.Msgid)
+		}
+		existing.Translation = msg.Translation
+	}
+	mergeMessage(existing, msg)
+	return nil
+}
+
+// addHeader parses the msgstr of msg into header fields and merges msg into
+// the header of the catalog.
+func (l *loader) addHeader(msg *Message) error {
+	header := &l.catalog.Header
+	if offset, ok := header.addFields(msg.Translation); !ok {
+		key := regexHeaderKeyValue.FindStringSubmatch(msg.Translation[offset:])[1]
+		return l.errorf(l.msgstrPosition(offset), `Invalid .po file. Found duplicate header key "%s".`, key)
+	}
+
+	if header.Position.Line == 0 {
+		header.Position = msg.Position
+	}
+	header.Translation += msg.Translation
+	mergeMessage(&header.Message, msg)
+	return nil
+}
+
+// newMessage converts the current key into a Message.
+func (l *loader) newMessage() (*Message, error) {
+	msg := &Message{
+		Msgctxt:            l.key.Msgctxt.String(),
+		Msgid:              l.key.Msgid.String(),
+		MsgidPlural:        l.key.MsgidPlural.String(),
+		Translation:        l.key.Msgstr.String(),
+		TranslatorComments: l.key.TranslatorComments,
+		ExtractedComments:  l.key.ExtractedComments,
+		References:         l.key.References,
+		Flags:              l.key.Flags,
+		Previous: Previous{
+			Msgctxt:     l.key.Previous.Msgctxt.String(),
+			Msgid:       l.key.Previous.Msgid.String(),
+			MsgidPlural: l.key.Previous.MsgidPlural.String(),
+		},
+		Position: Position{
+			Line:   l.key.Position.line,
+			Column: l.key.Position.column,
+		},
+	}
+
+	for idx := 0; idx < len(l.key.MsgstrPlural); idx++ {
+		plural, ok := l.key.MsgstrPlural[idx]
+		if !ok {
+			return nil, l.errorf(l.key.Position, `Invalid .po file. Found no msgstr[%d] for msgid "%s".`, idx, msg.Msgid)
+		}
+		msg.Plurals = append(msg.Plurals, plural.String())
+	}
+
+	return msg, nil
+}
+
+// mergeMessage merges the plurals, comments, flags, and previous msgids of src
+// into dst, which has the same msgctxt and msgid. The fuzzy flag of src is not
+// merged, so that a fuzzy duplicate doesn't mark dst as fuzzy.
+func mergeMessage(dst *Message, src *Message) {
+	if len(src.MsgidPlural) > 0 {
+		dst.MsgidPlural = src.MsgidPlural
+	}
+	if len(src.Plurals) > 0 {
+		dst.Plurals = src.Plurals
+	}
+	dst.TranslatorComments = append(dst.TranslatorComments, src.TranslatorComments...)
+	dst.ExtractedComments = append(dst.ExtractedComments, src.ExtractedComments...)
+	dst.References = append(dst.References, src.References...)
+	for _, flag := range src.Flags {
+		// The fuzzy flag only describes the entry that has it.
+		if flag != "fuzzy" && !dst.HasFlag(flag) {
+			dst.Flags = append(dst.Flags, flag)
+		}
+	}
+
+	if len(src.Previous.Msgctxt) > 0 {
+		dst.Previous.Msgctxt = src.Previous.Msgctxt
+	}
+	if len(src.Previous.Msgid) > 0 {
+		dst.Previous.Msgid = src.Previous.Msgid
+	}
+	if len(src.Previous.MsgidPlural) > 0 {
+		dst.Previous.MsgidPlural = src.Previous.MsgidPlural
+	}
+}
+
+// parseObsoleteLine parses the remainder of a "#~" line. Obsolete entries
+// have the same structure as active entries, including "#~|" previous
+// msgids, but they are stored separately.
+func (l *loader) parseObsoleteLine(line []byte) error {
+	line = bytes.TrimLeft(line, " \t")
+	if len(line) == 0 {
+		return nil
+	}
+
+	if line[0] == '|' {
+		return l.addPrevious(bytes.TrimLeft(line[1:], " \t"))
+	}
+
+	if l.state == stateUnspecified {
+		l.key.Obsolete = true
+	}
+
+	l.inObsolete = true
+	defer func() { l.inObsolete = false }()
+
+	return l.parseLine(line)
+}
+
+// addComment files a comment line under the kind of comment it is.
+func (l *loader) addComment(line []byte) error {
+	if submatch := regexExtractedComment.FindSubmatch(line); submatch != nil {
+		l.key.ExtractedComments = append(l.key.ExtractedComments, string(submatch[1]))
+		return nil
+	}
+
+	if submatch := regexReference.FindSubmatch(line); submatch != nil {
+		for _, reference := range bytes.Fields(submatch[1]) {
+			l.key.References = append(l.key.References, string(reference))
+		}
+		return nil
+	}
+
+	if submatch := regexFlags.FindSubmatch(line); submatch != nil {
+		for _, flag := range bytes.Split(submatch[1], []byte(",")) {
+			if flag = bytes.TrimSpace(flag); len(flag) > 0 {
+				l.key.Flags = append(l.key.Flags, string(flag))
 			}
-			msgidObj["translation"] = msgstr
 		}
+		return nil
 	}
-	if len(msgstrPlural) > 0 {
-		msgidObj["plurals"] = msgstrPlural
+
+	if submatch := regexPrevious.FindSubmatch(line); submatch != nil {
+		return l.addPrevious(submatch[1])
 	}
 
+	if submatch := regexTranslatorComment.FindSubmatch(line); submatch != nil {
+		l.key.TranslatorComments = append(l.key.TranslatorComments, string(submatch[1]))
+	}
 	return nil
 }
 
-func (l *loader) expectState() error {
+// addPrevious parses the contents of a "#|" comment. These mirror the
+// msgctxt, msgid, msgid_plural, and string continuation lines of an entry.
+func (l *loader) addPrevious(line []byte) error {
+	previous := &l.key.Previous
+
+	var loc []int
+	if loc = regexMsgctxt.FindSubmatchIndex(line); loc != nil {
+		previous.State = stateMsgctxt
+	} else if loc = regexMsgid.FindSubmatchIndex(line); loc != nil {
+		previous.State = stateMsgid
+	} else if loc = regexMsgidPlural.FindSubmatchIndex(line); loc != nil {
+		previous.State = stateMsgidPlural
+	} else if loc = regexString.FindSubmatchIndex(line); loc == nil {
+		return l.errorf(l.position(line, 0), "Invalid .po file. Found invalid previous comment %q.", line)
+	}
+
+	msg, err := l.unquote(line, loc[2], loc[3])
+	if err != nil {
+		return err
+	}
+
+	switch previous.State {
+	case stateMsgctxt:
+		previous.Msgctxt.WriteString(msg)
+	case stateMsgid:
+		previous.Msgid.WriteString(msg)
+	case stateMsgidPlural:
+		previous.MsgidPlural.WriteString(msg)
+	default:
+		return l.errorf(l.position(line, 0), "Encountered invalid state. Please ensure the input file is in a valid .po format.")
+	}
+	return nil
+}
+
+// pluralIndex parses the index found between start and end of a msgstr[N]
+// line and ensures that it is non-negative and unique within the current key.
+func (l *loader) pluralIndex(line []byte, start int, end int) (int, error) {
+	idxStr := string(line[start:end])
+	idx, err := strconv.Atoi(idxStr)
+	if err != nil {
+		return 0, l.errorf(l.position(line, start), "Invalid .po file. Found invalid plural index %s.", idxStr)
+	}
+
+	if idx < 0 {
+		return 0, l.errorf(l.position(line, start), "Invalid .po file. Found negative plural index %d.", idx)
+	}
+
+	if l.key.MsgstrPlural == nil {
+		l.key.MsgstrPlural = map[int]*strings.Builder{}
+	}
+
+	if _, ok := l.key.MsgstrPlural[idx]; ok {
+		return 0, l.errorf(l.position(line, start), "Invalid .po file. Found duplicate msgstr[%d].", idx)
+	}
+
+	return idx, nil
+}
+
+func (l *loader) expectState(line []byte) error {
 	if !l.nextStates[l.state] {
-		return errors.New(fmt.Sprintf("Invalid .po file. Found %s, expected one of %s.", stateStrings[l.state], l.printNextStates()))
+		err := l.errorf(l.position(line, 0), "Invalid .po file. Found %s, expected one of %s.", stateStrings[l.state], l.printNextStates())
+		err.Expected = l.sortedNextStates()
+		return err
 	}
 	return nil
 }
 
-func (l *loader) printNextStates() string {
+func (l *loader) sortedNextStates() []string {
 	states := []string{}
 	for state := range l.nextStates {
 		states = append(states, stateStrings[state])
 	}
 	sort.Strings(states)
+	return states
+}
+
+func (l *loader) printNextStates() string {
+	states := l.sortedNextStates()
 
 	ss := strings.Builder{}
 	ss.WriteRune('{')
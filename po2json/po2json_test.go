@@ -1,9 +1,15 @@
 package po2json
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -62,13 +68,101 @@ func (t *TestSuite) TestLoadBytes_Valid() {
 	t.NoError(enc.Encode(poJSON))
 }
 
+func (t *TestSuite) TestParseFile_ParseError() {
+	_, err := ParseFile("../testdata/invalid.po")
+	t.EqualError(err, "../testdata/invalid.po:7:1: Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
+
+	var parseErr *ParseError
+	t.True(errors.As(err, &parseErr))
+	t.Equal(&ParseError{
+		File:     "../testdata/invalid.po",
+		Line:     7,
+		Column:   1,
+		Text:     `msgid "Dialog title"`,
+		Expected: []string{"msgid_plural", "msgstr"},
+		Message:  "Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.",
+	}, parseErr)
+}
+
+func (t *TestSuite) TestLoadBytes_InvalidString() {
+	_, err := LoadBytes([]byte(`
+msgid "Log in"
+msgstr "Вой" "ти"
+`))
+	t.EqualError(err, `line 3, column 12: Invalid .po file. Found invalid string "Вой" "ти": unescaped double quote`)
+
+	var parseErr *ParseError
+	t.True(errors.As(err, &parseErr))
+	t.Equal(`msgstr "Вой" "ти"`, parseErr.Text)
+
+	var syntaxErr *SyntaxError
+	t.True(errors.As(err, &syntaxErr))
+	t.Equal(7, syntaxErr.Offset)
+}
+
+func (t *TestSuite) TestLoadBytes_InvalidStringObsolete() {
+	_, err := LoadBytes([]byte(`
+#~ msgid "Log out"
+#~ msgstr "Вы\йти"
+`))
+	t.EqualError(err, `line 3, column 14: Invalid .po file. Found invalid string "Вы\йти": invalid escape sequence \й`)
+}
+
+func (t *TestSuite) TestLoadFS_Valid() {
+	expected, err := LoadFile(poFilePath)
+	t.NoError(err)
+
+	poJSON, err := LoadFS(os.DirFS("../testdata"), "test.po")
+	t.NoError(err)
+	t.Equal(expected, poJSON)
+}
+
+func (t *TestSuite) TestLoadFS_FileNotFound() {
+	poJSON, err := LoadFS(fstest.MapFS{}, "test.po")
+	t.EqualError(err, "open test.po: file does not exist")
+	t.Nil(poJSON)
+}
+
+func (t *TestSuite) TestParseFS_ParseError() {
+	_, err := ParseFS(fstest.MapFS{
+		"locales/ru.po": &fstest.MapFile{Data: []byte("msgstr \"Войти\"\n")},
+	}, "locales/ru.po")
+	t.EqualError(err, "locales/ru.po:1:1: Invalid .po file. Found msgstr, expected one of {msgctxt, msgid}.")
+}
+
+func (t *TestSuite) TestLoadReader_Valid() {
+	expected, err := LoadFile(poFilePath)
+	t.NoError(err)
+
+	fileContents, err := ioutil.ReadFile(poFilePath)
+	t.NoError(err)
+
+	poJSON, err := LoadReader(iotest.OneByteReader(bytes.NewReader(fileContents)))
+	t.NoError(err)
+	t.Equal(expected, poJSON)
+}
+
+func (t *TestSuite) TestLoadReader_ReadError() {
+	poJSON, err := LoadReader(iotest.TimeoutReader(iotest.HalfReader(strings.NewReader(`
+msgid "Log in"
+msgstr "Войти"
+`))))
+	t.EqualError(err, iotest.ErrTimeout.Error())
+	t.Nil(poJSON)
+}
+
+func (t *TestSuite) TestParseReader_ParseError() {
+	_, err := ParseReader(strings.NewReader("msgid \"Log in\"\nmsgstr \"Войти\"\nmsgstr \"Вход\"\nmsgid \"Log out\""))
+	t.EqualError(err, "line 4, column 1: Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
+}
+
 func (t *TestSuite) TestLoadBytes_MsgctxtUnexpectedState() {
 	_, err := LoadBytes([]byte(`
 msgid "Log in"
 msgctxt "Войти"
 msgstr "derp"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgctxt, expected one of {msgid_plural, msgstr}.")
+	t.EqualError(err, "line 3, column 1: Invalid .po file. Found msgctxt, expected one of {msgid_plural, msgstr}.")
 }
 
 func (t *TestSuite) TestLoadBytes_MsgidUnexpectedState() {
@@ -78,7 +172,7 @@ msgstr "Войти"
 msgid "Dialog title"
 msgstr "Войти"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
+	t.EqualError(err, "line 4, column 1: Invalid .po file. Found msgid, expected one of {msgid_plural, msgstr}.")
 }
 
 func (t *TestSuite) TestLoadBytes_MsgstrUnexpectedState() {
@@ -89,7 +183,7 @@ msgstr "Войти"
 msgctxt "Dialog title"
 msgstr "Derp"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgstr, expected one of {msgid}.")
+	t.EqualError(err, "line 6, column 1: Invalid .po file. Found msgstr, expected one of {msgid}.")
 }
 
 func (t *TestSuite) TestLoadBytes_MsgidPluralUnexpectedState() {
@@ -99,7 +193,7 @@ msgid_plural "plural"
 msgid_plural "plural again"
 msgstr[0] "message"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgid_plural, expected one of {msgstr_plural}.")
+	t.EqualError(err, "line 4, column 1: Invalid .po file. Found msgid_plural, expected one of {msgstr_plural}.")
 }
 
 func (t *TestSuite) TestLoadBytes_MsgstrPluralUnexpectedState() {
@@ -107,7 +201,7 @@ func (t *TestSuite) TestLoadBytes_MsgstrPluralUnexpectedState() {
 msgid "singular"
 msgstr[0] "message"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgstr_plural, expected one of {msgid_plural, msgstr}.")
+	t.EqualError(err, "line 3, column 1: Invalid .po file. Found msgstr_plural, expected one of {msgid_plural, msgstr}.")
 }
 
 func (t *TestSuite) TestLoadBytes_AppendAllFields() {
@@ -127,7 +221,7 @@ msgstr ""
 
 ""
 `))
-	t.EqualError(err, "Encountered invalid state. Please ensure the input file is in a valid .po format.")
+	t.EqualError(err, "line 15, column 1: Encountered invalid state. Please ensure the input file is in a valid .po format.")
 }
 
 func (t *TestSuite) TestLoadBytes_EscapedQuotes() {
@@ -149,6 +243,225 @@ msgstr "This is a \"quoted\" string with a\nnewline."
 }`, string(s))
 }
 
+func (t *TestSuite) TestLoadBytes_Comments() {
+	po, err := LoadBytes([]byte(`
+# Translator comment.
+#
+#. Extracted comment.
+#: file.go:42 file.go:57
+#: other.go:1
+#, fuzzy
+msgid "Log in"
+msgstr "Войти"
+`))
+
+	t.NoError(err)
+	s, err := json.MarshalIndent(po, "", "    ")
+	t.NoError(err)
+	t.Equal(`{
+    "": {
+        "": {},
+        "Log in": {
+            "extractedComments": [
+                "Extracted comment."
+            ],
+            "flags": [
+                "fuzzy"
+            ],
+            "references": [
+                "file.go:42",
+                "file.go:57",
+                "other.go:1"
+            ],
+            "translation": "Войти",
+            "translatorComments": [
+                "Translator comment.",
+                ""
+            ]
+        }
+    }
+}`, string(s))
+}
+
+func (t *TestSuite) TestLoadBytes_Flags() {
+	po, err := LoadBytes([]byte(`
+#, fuzzy, c-format
+#, range: 1..5
+#,no-c-format
+msgid "%d apples"
+msgstr "%d pommes"
+`))
+
+	t.NoError(err)
+	t.Equal(
+		[]string{"fuzzy", "c-format", "range: 1..5", "no-c-format"},
+		po[""].(map[string]interface{})["%d apples"].(map[string]interface{})["flags"],
+	)
+}
+
+func (t *TestSuite) TestLoadBytes_Previous() {
+	po, err := LoadBytes([]byte(`
+#, fuzzy
+#| msgctxt "Old context"
+#| msgid "One apple "
+#| "was eaten."
+#| msgid_plural "%d apples were eaten."
+msgctxt "Context"
+msgid "One apple was eaten today."
+msgid_plural "%d apples were eaten today."
+msgstr[0] "Une pomme a été mangée."
+`))
+
+	t.NoError(err)
+	t.Equal(
+		map[string]interface{}{
+			"msgctxt":     "Old context",
+			"msgid":       "One apple was eaten.",
+			"msgidPlural": "%d apples were eaten.",
+		},
+		po["Context"].(map[string]interface{})["One apple was eaten today."].(map[string]interface{})["previous"],
+	)
+}
+
+func (t *TestSuite) TestLoadBytes_PreviousInvalidState() {
+	_, err := LoadBytes([]byte(`
+#| "was eaten."
+msgid "One apple was eaten."
+msgstr "Une pomme a été mangée."
+`))
+	t.EqualError(err, "line 2, column 4: Encountered invalid state. Please ensure the input file is in a valid .po format.")
+}
+
+func (t *TestSuite) TestLoadBytes_PreviousInvalid() {
+	_, err := LoadBytes([]byte(`
+#| msgstr "Une pomme"
+msgid "One apple was eaten."
+msgstr "Une pomme a été mangée."
+`))
+	t.EqualError(err, `line 2, column 4: Invalid .po file. Found invalid previous comment "msgstr \"Une pomme\"".`)
+}
+
+func (t *TestSuite) TestParseBytes_Obsolete() {
+	catalog, err := ParseBytes([]byte(`
+msgid "Log in"
+msgstr "Войти"
+
+# Translator comment.
+#~ msgid "Log out"
+#~ msgstr "Выйти"
+
+#, fuzzy
+#~| msgctxt "Old context"
+#~| msgid "Register"
+#~ msgctxt "Button label"
+#~ msgid ""
+#~ "Sign "
+#~ "up"
+#~ msgid_plural "Sign ups"
+#~ msgstr[0] "Регистрация"
+#~ msgstr[1] "Регистрации"
+`))
+
+	t.NoError(err)
+	t.Equal([]*Message{
+		{
+			Msgid:       "Log in",
+			Translation: "Войти",
+			Position:    Position{Line: 2, Column: 1},
+		},
+	}, catalog.Messages)
+	t.Equal([]*Message{
+		{
+			Msgid:              "Log out",
+			Translation:        "Выйти",
+			TranslatorComments: []string{"Translator comment."},
+			Position:           Position{Line: 6, Column: 4},
+		},
+		{
+			Msgctxt:     "Button label",
+			Msgid:       "Sign up",
+			MsgidPlural: "Sign ups",
+			Plurals:     []string{"Регистрация", "Регистрации"},
+			Flags:       []string{"fuzzy"},
+			Previous: Previous{
+				Msgctxt: "Old context",
+				Msgid:   "Register",
+			},
+			Position: Position{Line: 12, Column: 4},
+		},
+	}, catalog.Obsolete)
+}
+
+func (t *TestSuite) TestParseBytes_ObsoleteMixedWithActive() {
+	_, err := ParseBytes([]byte(`
+#~ msgid "Log out"
+msgstr "Выйти"
+`))
+	t.EqualError(err, "line 3, column 1: Invalid .po file. Found obsolete and active lines in the same entry.")
+
+	_, err = ParseBytes([]byte(`
+msgid "Log out"
+#~ msgstr "Выйти"
+`))
+	t.EqualError(err, "line 3, column 4: Invalid .po file. Found obsolete and active lines in the same entry.")
+}
+
+func (t *TestSuite) TestLoadBytes_PluralIndexOutOfOrder() {
+	po, err := LoadBytes([]byte(`
+msgid "singular"
+msgid_plural "plural"
+msgstr[2] "two"
+msgstr[0] "ze"
+"ro"
+msgstr[1] "one"
+`))
+
+	t.NoError(err)
+	t.Equal(
+		[]string{"zero", "one", "two"},
+		po[""].(map[string]interface{})["singular"].(map[string]interface{})["plurals"],
+	)
+}
+
+func (t *TestSuite) TestLoadBytes_PluralIndexGap() {
+	_, err := LoadBytes([]byte(`
+msgid "singular"
+msgid_plural "plural"
+msgstr[0] "zero"
+msgstr[2] "two"
+`))
+	t.EqualError(err, `line 2, column 1: Invalid .po file. Found no msgstr[1] for msgid "singular".`)
+}
+
+func (t *TestSuite) TestLoadBytes_PluralIndexDuplicate() {
+	_, err := LoadBytes([]byte(`
+msgid "singular"
+msgid_plural "plural"
+msgstr[0] "zero"
+msgstr[1] "one"
+msgstr[0] "zero again"
+`))
+	t.EqualError(err, "line 6, column 8: Invalid .po file. Found duplicate msgstr[0].")
+}
+
+func (t *TestSuite) TestLoadBytes_PluralIndexNegative() {
+	_, err := LoadBytes([]byte(`
+msgid "singular"
+msgid_plural "plural"
+msgstr[-1] "minus one"
+`))
+	t.EqualError(err, "line 4, column 8: Invalid .po file. Found negative plural index -1.")
+}
+
+func (t *TestSuite) TestLoadBytes_PluralIndexInvalid() {
+	_, err := LoadBytes([]byte(`
+msgid "singular"
+msgid_plural "plural"
+msgstr[99999999999999999999] "too big"
+`))
+	t.EqualError(err, "line 4, column 8: Invalid .po file. Found invalid plural index 99999999999999999999.")
+}
+
 func (t *TestSuite) TestLoadBytes_DuplicateMsgid() {
 	_, err := LoadBytes([]byte(`
 msgid "Log in"
@@ -157,7 +470,7 @@ msgstr "Войти"
 msgid "Log in"
 msgstr "Войти"
 `))
-	t.EqualError(err, `Invalid .po file. Found duplicate msgstr for msgid "Log in".`)
+	t.EqualError(err, `line 6, column 1: Invalid .po file. Found duplicate msgstr for msgid "Log in".`)
 }
 
 func (t *TestSuite) TestLoadBytes_DuplicateHeaderKey() {
@@ -167,14 +480,14 @@ msgstr ""
 "HeaderKey: Header value\n"
 "HeaderKey: Another value\n"
 `))
-	t.EqualError(err, `Invalid .po file. Found duplicate header key "HeaderKey".`)
+	t.EqualError(err, `line 5, column 2: Invalid .po file. Found duplicate header key "HeaderKey".`)
 }
 
 func (t *TestSuite) TestLoadBytes_MissingMsgid0() {
 	_, err := LoadBytes([]byte(`
 msgstr "Войти"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgstr, expected one of {msgctxt, msgid}.")
+	t.EqualError(err, "line 2, column 1: Invalid .po file. Found msgstr, expected one of {msgctxt, msgid}.")
 }
 
 func (t *TestSuite) TestLoadBytes_MissingMsgid1() {
@@ -182,7 +495,7 @@ func (t *TestSuite) TestLoadBytes_MissingMsgid1() {
 msgctxt "Dialog title"
 msgstr "Войти"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgstr, expected one of {msgid}.")
+	t.EqualError(err, "line 3, column 1: Invalid .po file. Found msgstr, expected one of {msgid}.")
 }
 
 func (t *TestSuite) TestLoadBytes_DuplicateMsgctxt() {
@@ -190,7 +503,7 @@ func (t *TestSuite) TestLoadBytes_DuplicateMsgctxt() {
 msgctxt "Dialog title"
 msgctxt "Dialog title"
 `))
-	t.EqualError(err, "Invalid .po file. Found msgctxt, expected one of {msgid}.")
+	t.EqualError(err, "line 3, column 1: Invalid .po file. Found msgctxt, expected one of {msgid}.")
 }
 
 func BenchmarkLoadBytes(b *testing.B) {
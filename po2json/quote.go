@@ -0,0 +1,155 @@
+package po2json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes a string that is not a valid quoted .po string.
+type SyntaxError struct {
+	// Offset is the byte offset within the quoted string, including the
+	// opening quote, at which the problem was found.
+	Offset int
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns the description of the problem.
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// Unquote interprets s as a double-quoted .po string and returns the string
+// that s quotes.
+//
+// Escape sequences follow the rules of GNU gettext, which are those of C:
+// \a, \b, \f, \n, \r, \t, \v, \\, \", \', and \? stand for the corresponding
+// characters, \ooo is an octal escape of one to three digits, and \xhh is a
+// hexadecimal escape of one or more digits. Octal and hexadecimal escapes
+// stand for a single byte. Any other escape sequence is an error.
+//
+// Errors are reported as a *SyntaxError that points at the offending byte.
+func Unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' {
+		return "", &SyntaxError{Offset: 0, Message: "missing opening double quote"}
+	}
+	if s[len(s)-1] != '"' {
+		return "", &SyntaxError{Offset: len(s) - 1, Message: "missing closing double quote"}
+	}
+
+	body := s[1 : len(s)-1]
+	if !strings.ContainsAny(body, `\"`) {
+		return body, nil
+	}
+
+	ss := strings.Builder{}
+	for idx := 0; idx < len(body); idx++ {
+		c := body[idx]
+		if c == '"' {
+			return "", &SyntaxError{Offset: idx + 1, Message: "unescaped double quote"}
+		}
+		if c != '\\' {
+			ss.WriteByte(c)
+			continue
+		}
+
+		start := idx
+		idx++
+		if idx == len(body) {
+			return "", &SyntaxError{Offset: start + 1, Message: "incomplete escape sequence"}
+		}
+
+		switch c = body[idx]; c {
+		case 'a':
+			ss.WriteByte('\a')
+		case 'b':
+			ss.WriteByte('\b')
+		case 'f':
+			ss.WriteByte('\f')
+		case 'n':
+			ss.WriteByte('\n')
+		case 'r':
+			ss.WriteByte('\r')
+		case 't':
+			ss.WriteByte('\t')
+		case 'v':
+			ss.WriteByte('\v')
+		case '\\', '"', '\'', '?':
+			ss.WriteByte(c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := idx + 1
+			for end < len(body) && end < idx+3 && isOctalDigit(body[end]) {
+				end++
+			}
+			value, err := strconv.ParseUint(body[idx:end], 8, 8)
+			if err != nil {
+				return "", &SyntaxError{Offset: start + 1, Message: fmt.Sprintf("octal escape sequence %s is out of range", body[start:end])}
+			}
+			ss.WriteByte(byte(value))
+			idx = end - 1
+		case 'x':
+			end := idx + 1
+			for end < len(body) && isHexDigit(body[end]) {
+				end++
+			}
+			if end == idx+1 {
+				return "", &SyntaxError{Offset: start + 1, Message: `hexadecimal escape sequence \x has no digits`}
+			}
+			value, err := strconv.ParseUint(body[idx+1:end], 16, 8)
+			if err != nil {
+				return "", &SyntaxError{Offset: start + 1, Message: fmt.Sprintf("hexadecimal escape sequence %s is out of range", body[start:end])}
+			}
+			ss.WriteByte(byte(value))
+			idx = end - 1
+		default:
+			_, size := utf8.DecodeRuneInString(body[idx:])
+			return "", &SyntaxError{Offset: start + 1, Message: fmt.Sprintf("invalid escape sequence %s", body[start:idx+size])}
+		}
+	}
+	return ss.String(), nil
+}
+
+// Quote returns s as a double-quoted .po string. Backslashes, double quotes,
+// and the control characters that have a named escape sequence are escaped.
+// All other bytes are written as they are, as GNU gettext does.
+func Quote(s string) string {
+	ss := strings.Builder{}
+	ss.Grow(len(s) + 2)
+	ss.WriteByte('"')
+	for idx := 0; idx < len(s); idx++ {
+		switch c := s[idx]; c {
+		case '\a':
+			ss.WriteString(`\a`)
+		case '\b':
+			ss.WriteString(`\b`)
+		case '\f':
+			ss.WriteString(`\f`)
+		case '\n':
+			ss.WriteString(`\n`)
+		case '\r':
+			ss.WriteString(`\r`)
+		case '\t':
+			ss.WriteString(`\t`)
+		case '\v':
+			ss.WriteString(`\v`)
+		case '\\':
+			ss.WriteString(`\\`)
+		case '"':
+			ss.WriteString(`\"`)
+		default:
+			ss.WriteByte(c)
+		}
+	}
+	ss.WriteByte('"')
+	return ss.String()
+}
+
+func isOctalDigit(c byte) bool {
+	return '0' <= c && c <= '7'
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,84 @@
+package po2json
+
+import (
+	"errors"
+)
+
+func (t *TestSuite) TestUnquote_Valid() {
+	for quoted, expected := range map[string]string{
+		`""`:                 "",
+		`"Войти"`:            "Войти",
+		`"a\"b\"c"`:          `a"b"c`,
+		`"\a\b\f\n\r\t\v"`:   "\a\b\f\n\r\t\v",
+		`"\\ \' \?"`:         `\ ' ?`,
+		`"\0"`:               "\x00",
+		`"\7\07\007\0070"`:   "\a\a\a\a0",
+		`"\101\102C"`:        "ABC",
+		`"\377"`:             "\xff",
+		`"\x41\x4a\x4B"`:     "AJK",
+		`"\x0041"`:           "A",
+		`"\320\222\xd0\xbe"`: "Во",
+		`"tab	and space "`:   "tab\tand space ",
+	} {
+		actual, err := Unquote(quoted)
+		t.NoError(err, quoted)
+		t.Equal(expected, actual, quoted)
+	}
+}
+
+func (t *TestSuite) TestUnquote_Invalid() {
+	for quoted, expected := range map[string]SyntaxError{
+		``:             {Offset: 0, Message: "missing opening double quote"},
+		`Войти"`:       {Offset: 0, Message: "missing opening double quote"},
+		`"Войти`:       {Offset: 10, Message: "missing closing double quote"},
+		`"Вой"ти"`:     {Offset: 7, Message: "unescaped double quote"},
+		`"\"`:          {Offset: 1, Message: "incomplete escape sequence"},
+		`"ab\u0041"`:   {Offset: 3, Message: `invalid escape sequence \u`},
+		`"\U00000041"`: {Offset: 1, Message: `invalid escape sequence \U`},
+		`"\й"`:         {Offset: 1, Message: `invalid escape sequence \й`},
+		`"\8"`:         {Offset: 1, Message: `invalid escape sequence \8`},
+		`"\400"`:       {Offset: 1, Message: `octal escape sequence \400 is out of range`},
+		`"\x"`:         {Offset: 1, Message: `hexadecimal escape sequence \x has no digits`},
+		`"\x100"`:      {Offset: 1, Message: `hexadecimal escape sequence \x100 is out of range`},
+	} {
+		actual, err := Unquote(quoted)
+		t.Empty(actual, quoted)
+
+		var syntaxErr *SyntaxError
+		t.True(errors.As(err, &syntaxErr), quoted)
+		t.Equal(expected, *syntaxErr, quoted)
+	}
+}
+
+func (t *TestSuite) TestQuote() {
+	for unquoted, expected := range map[string]string{
+		"":                     `""`,
+		"Войти":                `"Войти"`,
+		`a "quoted" \ string`:  `"a \"quoted\" \\ string"`,
+		"\a\b\f\n\r\t\v":       `"\a\b\f\n\r\t\v"`,
+		"' ? \x00 \x7f \xff %": "\"' ? \x00 \x7f \xff %\"",
+	} {
+		t.Equal(expected, Quote(unquoted), unquoted)
+
+		actual, err := Unquote(Quote(unquoted))
+		t.NoError(err, unquoted)
+		t.Equal(unquoted, actual)
+	}
+}
+
+func (t *TestSuite) TestLoadBytes_CEscapes() {
+	poJSON, err := LoadBytes([]byte(`
+msgid "It\'s \"quoted\"\n"
+msgstr "\320\222\320\276\320\271\321\202\320\270 \x41\n"
+`))
+	t.NoError(err)
+	t.Equal("Войти A\n", poJSON[""].(map[string]interface{})["It's \"quoted\"\n"].(map[string]interface{})["translation"])
+}
+
+func (t *TestSuite) TestLoadBytes_InvalidEscape() {
+	_, err := LoadBytes([]byte(`
+msgid "Log in"
+msgstr "Вой\u0442и"
+`))
+	t.EqualError(err, `line 3, column 12: Invalid .po file. Found invalid string "Вой\u0442и": invalid escape sequence \u`)
+}
@@ -0,0 +1,96 @@
+package gogettext
+
+import (
+	"sync"
+
+	"github.com/taylor-s-dean/gogettext/po2json"
+)
+
+// store is the backend that holds the messages of a MessageCatalog.
+type store interface {
+	// Lookup returns the message with the specified msgctxt and msgid.
+	Lookup(msgctxt string, msgid string) (*po2json.Message, bool)
+	// HasContext reports whether any message has the specified msgctxt.
+	HasContext(msgctxt string) bool
+	// header returns the header, which must not be modified.
+	header() *po2json.Header
+	// messages calls fn with every active message of the store, which must
+	// not be modified, and returns the first error returned by fn.
+	messages(fn func(msg *po2json.Message) error) error
+	// obsolete returns the obsolete entries of the store, which must not be
+	// modified.
+	obsolete() []*po2json.Message
+	// load returns every message of the store as a Catalog, which must not be
+	// modified.
+	load() (*po2json.Catalog, error)
+	// close releases the resources held by the store.
+	close() error
+}
+
+// catalogStore is a store that holds a Catalog parsed into memory.
+type catalogStore struct {
+	*po2json.Catalog
+}
+
+func (cs catalogStore) header() *po2json.Header {
+	return &cs.Header
+}
+
+func (cs catalogStore) messages(fn func(msg *po2json.Message) error) error {
+	for _, msg := range cs.Messages {
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs catalogStore) obsolete() []*po2json.Message {
+	return cs.Obsolete
+}
+
+func (cs catalogStore) load() (*po2json.Catalog, error) {
+	return cs.Catalog, nil
+}
+
+func (cs catalogStore) close() error {
+	return nil
+}
+
+// moStore is a store that looks messages up in a memory-mapped .mo file.
+// The file is only parsed into a Catalog by load, once.
+type moStore struct {
+	*po2json.MOFile
+	hdr     po2json.Header
+	once    sync.Once
+	catalog *po2json.Catalog
+	err     error
+}
+
+func newMOStore(f *po2json.MOFile) *moStore {
+	return &moStore{MOFile: f, hdr: f.Header()}
+}
+
+func (ms *moStore) header() *po2json.Header {
+	return &ms.hdr
+}
+
+func (ms *moStore) messages(fn func(msg *po2json.Message) error) error {
+	return ms.Messages(fn)
+}
+
+// obsolete returns nil because .mo files don't contain obsolete entries.
+func (ms *moStore) obsolete() []*po2json.Message {
+	return nil
+}
+
+func (ms *moStore) load() (*po2json.Catalog, error) {
+	ms.once.Do(func() {
+		ms.catalog, ms.err = ms.Catalog()
+	})
+	return ms.catalog, ms.err
+}
+
+func (ms *moStore) close() error {
+	return ms.Close()
+}